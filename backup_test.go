@@ -0,0 +1,129 @@
+package gokv
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackupDuringWrites(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, filepath.Join(dir, "test.db"), &Options{PageSize: 8192})
+	defer db.Close()
+
+	value := func(round, i int) []byte {
+		return []byte(fmt.Sprintf("v%d-%s", round, strings.Repeat("z", i%3000)))
+	}
+	for round := 0; round < 5; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 1000; i++ {
+				if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), value(round, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Keep rewriting keys while the backups are taken
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for round := 5; ; round++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			err := db.Update(func(tx *Tx) error {
+				for i := 0; i < 1000; i += 3 {
+					if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), value(round, i)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	var snapshot string
+	copied := filepath.Join(dir, "copied.db")
+	err := db.View(func(tx *Tx) error {
+		v, err := tx.Get([]byte("k00003"))
+		if err != nil {
+			return err
+		}
+		snapshot = string(v)
+		// Give the writer time to commit over the snapshot
+		time.Sleep(50 * time.Millisecond)
+		return tx.CopyFile(copied, 0600)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(db.BackupHandler())
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	srv.Close()
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != int64(len(body)) {
+		t.Fatalf("backup response %s with %d of %d bytes", resp.Status, len(body), resp.ContentLength)
+	}
+
+	downloaded := filepath.Join(dir, "downloaded.db")
+	if err := os.WriteFile(downloaded, body, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{copied, downloaded} {
+		backup := openTestDB(t, path, nil)
+		checkPageAccounting(t, backup)
+		backup.View(func(tx *Tx) error {
+			n := 0
+			c := tx.Cursor()
+			for k, _ := c.First(); k != nil; k, _ = c.Next() {
+				n++
+			}
+			if err := c.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if n != 1000 {
+				t.Fatalf("%s has %d keys, want 1000", filepath.Base(path), n)
+			}
+
+			if path == copied {
+				v, err := tx.Get([]byte("k00003"))
+				if err != nil || string(v) != snapshot {
+					t.Fatalf("copied backup does not hold the snapshot it was taken from: %v", err)
+				}
+			}
+			return nil
+		})
+		backup.Close()
+	}
+}
@@ -0,0 +1,125 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultMaxBatchSize is the number of functions after which a batch is committed without waiting.
+	DefaultMaxBatchSize = 1000
+
+	// DefaultMaxBatchDelay is how long a batch waits for more functions before it is committed.
+	DefaultMaxBatchDelay = 10 * time.Millisecond
+)
+
+// errRunAlone marks a function that failed in a shared transaction. Its caller runs it again in a
+// transaction of its own, so it gets the function's own result.
+var errRunAlone = errors.New("batched function failed, run it alone")
+
+// writeGroup collects the functions of concurrent Batch calls that are committed in one transaction.
+// The first caller to join a group leads it: it waits for more functions and commits them all.
+type writeGroup struct {
+	fns  []func(*Tx) error
+	full chan struct{} // closed when the group reaches MaxBatchSize and takes no more functions
+	done chan struct{} // closed once the group has been committed and errs is set
+
+	// errs holds the result of each function, or errRunAlone for those its caller must run again
+	errs []error
+}
+
+// Batch calls fn in a write transaction that it may share with other goroutines calling Batch,
+// so that concurrent writers pay for one commit together instead of one each. A batch is
+// committed MaxBatchDelay after its first function arrives, or as soon as it holds MaxBatchSize.
+//
+// When fn returns an error or panics, the shared transaction is retried without it, and fn is run
+// again on its own with Update, whose result Batch returns. fn can therefore run more than once and
+// should have no effects outside the transaction.
+func (db *DB) Batch(fn func(*Tx) error) error {
+	db.batchMu.Lock()
+	g := db.batch
+	leader := g == nil
+	if leader {
+		g = &writeGroup{full: make(chan struct{}), done: make(chan struct{})}
+		db.batch = g
+	}
+	index := len(g.fns)
+	g.fns = append(g.fns, fn)
+	if len(g.fns) >= db.MaxBatchSize {
+		// Later callers start the next group
+		db.batch = nil
+		close(g.full)
+	}
+	db.batchMu.Unlock()
+
+	if leader {
+		db.commitGroup(g)
+	} else {
+		<-g.done
+	}
+
+	if g.errs[index] == errRunAlone {
+		return db.Update(fn)
+	}
+	return g.errs[index]
+}
+
+// commitGroup waits until the group is full or MaxBatchDelay has passed, and then commits its functions
+// in one transaction, leaving out the ones that fail until the rest go through.
+func (db *DB) commitGroup(g *writeGroup) {
+	timer := time.NewTimer(db.MaxBatchDelay)
+	select {
+	case <-timer.C:
+	case <-g.full:
+		timer.Stop()
+	}
+
+	// Close the group to newcomers. Once it is detached under the lock, g.fns no longer changes.
+	db.batchMu.Lock()
+	if db.batch == g {
+		db.batch = nil
+	}
+	db.batchMu.Unlock()
+
+	g.errs = make([]error, len(g.fns))
+	for {
+		failed := -1
+		err := db.Update(func(tx *Tx) error {
+			for i, fn := range g.fns {
+				if g.errs[i] == errRunAlone {
+					continue
+				}
+				if err := callRecovered(fn, tx); err != nil {
+					failed = i
+					return err
+				}
+			}
+			return nil
+		})
+
+		if failed < 0 {
+			// Every remaining function shares the outcome of the commit
+			for i := range g.errs {
+				if g.errs[i] == nil {
+					g.errs[i] = err
+				}
+			}
+			break
+		}
+		g.errs[failed] = errRunAlone
+	}
+
+	close(g.done)
+}
+
+// callRecovered calls fn and returns a panic as an error, so the shared transaction is rolled back
+// normally. The function then panics again in its own caller when it is run alone.
+func callRecovered(fn func(*Tx) error, tx *Tx) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("batched function panicked: %v", r)
+		}
+	}()
+	return fn(tx)
+}
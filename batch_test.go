@@ -0,0 +1,102 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	const n = 200
+	failing := func(i int) bool { return i%50 == 7 }
+	const panicking = 99
+
+	start := db.Meta.TxID
+	errs := make([]error, n)
+	panics := make([]any, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A panicking function is retried on its own, where the panic reaches its caller
+			defer func() { panics[i] = recover() }()
+
+			errs[i] = db.Batch(func(tx *Tx) error {
+				if failing(i) {
+					return fmt.Errorf("fail %d", i)
+				}
+				if i == panicking {
+					panic("boom")
+				}
+				return tx.Put([]byte(fmt.Sprintf("k%03d", i)), []byte("v"))
+			})
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		switch {
+		case i == panicking:
+			if panics[i] != "boom" {
+				t.Errorf("call %d: recovered %v, want the panic", i, panics[i])
+			}
+		case failing(i):
+			if errs[i] == nil || errs[i].Error() != fmt.Sprintf("fail %d", i) {
+				t.Errorf("call %d: got %v, want its own error", i, errs[i])
+			}
+		default:
+			if errs[i] != nil || panics[i] != nil {
+				t.Errorf("call %d: got %v, %v", i, errs[i], panics[i])
+			}
+		}
+	}
+
+	db.View(func(tx *Tx) error {
+		for i := 0; i < n; i++ {
+			_, err := tx.Get([]byte(fmt.Sprintf("k%03d", i)))
+			if stored := !failing(i) && i != panicking; stored && err != nil || !stored && !errors.Is(err, ErrKeyNotFound) {
+				t.Errorf("Get(k%03d) = %v", i, err)
+			}
+		}
+		return nil
+	})
+
+	if commits := db.Meta.TxID - start; commits > 20 {
+		t.Fatalf("%d commits for %d concurrent calls, want them batched", commits, n)
+	}
+}
+
+func TestBatchCommitsWhenFull(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	// Only a full batch can be committed before the test times out
+	db.MaxBatchSize = 10
+	db.MaxBatchDelay = time.Hour
+
+	start := db.Meta.TxID
+	var wg sync.WaitGroup
+	for i := 0; i < 3*db.MaxBatchSize; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.Batch(func(tx *Tx) error {
+				return tx.Put([]byte(fmt.Sprintf("k%03d", i)), []byte("v"))
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if commits := db.Meta.TxID - start; commits != 3 {
+		t.Fatalf("%d commits for three full batches, want 3", commits)
+	}
+}
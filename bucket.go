@@ -0,0 +1,308 @@
+package gokv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Bucket is a named collection of key-value pairs with its own B+ tree.
+// The root page of the tree is stored as a bucket entry in the parent's tree,
+// so buckets can be nested inside other buckets.
+type Bucket struct {
+	tx     *Tx
+	parent *Bucket // nil for the top level of the database
+	name   []byte
+}
+
+// rootBucket returns the top level of the database as a bucket.
+func (tx *Tx) rootBucket() *Bucket {
+	return &Bucket{tx: tx}
+}
+
+// CreateBucket creates a new bucket at the top level of the database.
+func (tx *Tx) CreateBucket(name []byte) (*Bucket, error) {
+	return tx.rootBucket().CreateBucket(name)
+}
+
+// Bucket returns the bucket with the given name at the top level of the database.
+func (tx *Tx) Bucket(name []byte) (*Bucket, error) {
+	return tx.rootBucket().Bucket(name)
+}
+
+// DeleteBucket deletes a bucket at the top level of the database, along with everything stored in it.
+func (tx *Tx) DeleteBucket(name []byte) error {
+	return tx.rootBucket().DeleteBucket(name)
+}
+
+// Get retrieves the value associated with the given key from the bucket.
+// Like every read, it starts from the transaction's current root, so it sees the transaction's own writes.
+func (b *Bucket) Get(key []byte) ([]byte, error) {
+	root, err := b.rootPage()
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := b.tx.findLeaf(root, key)
+	if err != nil {
+		return nil, err
+	}
+	index, found := leaf.findKeyInNode(key)
+
+	if !found {
+		return nil, ErrKeyNotFound
+	}
+
+	_, value := leaf.getLeafKeyValue(index)
+
+	if leaf.getFlags(index)&EntryFlagBucket != 0 {
+		return nil, ErrIncompatibleValue
+	}
+
+	if leaf.getFlags(index)&EntryFlagOverflow != 0 {
+		return b.tx.readOverflow(value)
+	}
+
+	result := make([]byte, len(value))
+	copy(result, value)
+
+	return result, nil
+}
+
+// Put inserts or updates a key-value pair in the bucket.
+// Values larger than a quarter of the page size are stored in a chain of overflow pages.
+func (b *Bucket) Put(key []byte, value []byte) error {
+	return b.put(key, value, putUpsert)
+}
+
+// Insert adds a key-value pair to the bucket, failing if the key already exists.
+func (b *Bucket) Insert(key []byte, value []byte) error {
+	return b.put(key, value, putInsert)
+}
+
+// Replace overwrites the value of an existing key in the bucket, failing if the key does not exist.
+func (b *Bucket) Replace(key []byte, value []byte) error {
+	return b.put(key, value, putReplace)
+}
+
+// Delete removes a key from the bucket.
+func (b *Bucket) Delete(key []byte) error {
+	if b.tx.db == nil {
+		return ErrTxClosed
+	}
+	if !b.tx.writable {
+		return fmt.Errorf("cannot delete: %w", ErrTxNotWritable)
+	}
+
+	root, err := b.rootPage()
+	if err != nil {
+		return err
+	}
+
+	newRoot, err := b.tx.deleteFromTree(root, key, false)
+	if err != nil {
+		return err
+	}
+
+	return b.setRootPage(root, newRoot)
+}
+
+// Cursor returns a new cursor over the bucket's keys. Nested buckets show up with a nil value.
+func (b *Bucket) Cursor() *Cursor {
+	return &Cursor{tx: b.tx, bucket: b}
+}
+
+// CreateBucket creates a new bucket nested in this one, backed by a fresh empty tree.
+func (b *Bucket) CreateBucket(name []byte) (*Bucket, error) {
+	if b.tx.db == nil {
+		return nil, ErrTxClosed
+	}
+	if !b.tx.writable {
+		return nil, fmt.Errorf("cannot create bucket: %w", ErrTxNotWritable)
+	}
+	if len(name) == 0 {
+		return nil, ErrBucketNameRequired
+	}
+	if len(name) > MaxKeySize(b.tx.pageSize()) {
+		return nil, ErrKeyTooLarge
+	}
+
+	rootID := b.tx.allocateNode()
+	rootNode := &Node{data: make([]byte, b.tx.pageSize())}
+	rootNode.data[0] = byte(NodeLeaf)
+	b.tx.dirtyNodes[rootID] = rootNode
+
+	err := b.putEntry(name, encodePageID(rootID), EntryFlagBucket, putInsert)
+	if err != nil {
+		b.tx.freePage(rootID)
+		if errors.Is(err, ErrKeyExists) {
+			return nil, ErrBucketExists
+		}
+		return nil, err
+	}
+
+	return &Bucket{tx: b.tx, parent: b, name: cloneBytes(name)}, nil
+}
+
+// Bucket returns the bucket with the given name nested in this one.
+func (b *Bucket) Bucket(name []byte) (*Bucket, error) {
+	child := &Bucket{tx: b.tx, parent: b, name: cloneBytes(name)}
+	if _, err := child.rootPage(); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+// DeleteBucket deletes a bucket nested in this one, freeing every page of its tree and of the buckets inside it.
+func (b *Bucket) DeleteBucket(name []byte) error {
+	if b.tx.db == nil {
+		return ErrTxClosed
+	}
+	if !b.tx.writable {
+		return fmt.Errorf("cannot delete bucket: %w", ErrTxNotWritable)
+	}
+
+	child := &Bucket{tx: b.tx, parent: b, name: name}
+	childRoot, err := child.rootPage()
+	if err != nil {
+		return err
+	}
+
+	root, err := b.rootPage()
+	if err != nil {
+		return err
+	}
+
+	newRoot, err := b.tx.deleteFromTree(root, name, true)
+	if err != nil {
+		return err
+	}
+
+	err = b.tx.freeTree(childRoot)
+	if err != nil {
+		return err
+	}
+
+	return b.setRootPage(root, newRoot)
+}
+
+// put writes a user value into the bucket, spilling it into overflow pages if it is too large.
+func (b *Bucket) put(key []byte, value []byte, mode putMode) error {
+	if b.tx.db == nil {
+		return ErrTxClosed
+	}
+	if !b.tx.writable {
+		return fmt.Errorf("cannot put: %w", ErrTxNotWritable)
+	}
+	if len(key) == 0 {
+		return ErrKeyRequired
+	}
+	if len(key) > MaxKeySize(b.tx.pageSize()) {
+		return ErrKeyTooLarge
+	}
+	if uint64(len(value)) > MaxValueSize {
+		return ErrValueTooLarge
+	}
+
+	var flags byte
+	if len(value) > maxInlineValueSize(b.tx.pageSize()) {
+		value = b.tx.writeOverflow(value)
+		flags = EntryFlagOverflow
+	}
+
+	err := b.putEntry(key, value, flags, mode)
+	if err != nil && flags&EntryFlagOverflow != 0 {
+		b.tx.freeOverflow(value)
+	}
+	return err
+}
+
+// putEntry writes a raw entry into the bucket's tree and records the tree's new root in the parent.
+func (b *Bucket) putEntry(key []byte, value []byte, flags byte, mode putMode) error {
+	root, err := b.rootPage()
+	if err != nil {
+		return err
+	}
+
+	newRoot, err := b.tx.putInTree(root, key, value, flags, mode)
+	if err != nil {
+		return err
+	}
+
+	return b.setRootPage(root, newRoot)
+}
+
+// rootPage returns the root page of the bucket's tree. It is looked up in the parent's tree every time,
+// so all handles to the same bucket see the latest root within the transaction.
+func (b *Bucket) rootPage() (int, error) {
+	if b.tx.db == nil {
+		return 0, ErrTxClosed
+	}
+	if b.parent == nil {
+		return b.tx.root, nil
+	}
+
+	parentRoot, err := b.parent.rootPage()
+	if err != nil {
+		return 0, err
+	}
+
+	leaf, err := b.tx.findLeaf(parentRoot, b.name)
+	if err != nil {
+		return 0, err
+	}
+
+	index, found := leaf.findKeyInNode(b.name)
+	if !found {
+		return 0, ErrBucketNotFound
+	}
+	if leaf.getFlags(index)&EntryFlagBucket == 0 {
+		return 0, ErrIncompatibleValue
+	}
+
+	_, value := leaf.getLeafKeyValue(index)
+	return int(binary.LittleEndian.Uint64(value)), nil
+}
+
+// setRootPage records that the bucket's tree moved from oldRoot to newRoot, rewriting the bucket entry
+// in the parent's tree. That may move the parent's root in turn, up to the transaction's root.
+func (b *Bucket) setRootPage(oldRoot int, newRoot int) error {
+	if oldRoot == newRoot {
+		return nil
+	}
+
+	if b.parent == nil {
+		b.tx.root = newRoot
+		return nil
+	}
+
+	return b.parent.putEntry(b.name, encodePageID(newRoot), EntryFlagBucket, putReplace)
+}
+
+// freeTree frees every page of the tree rooted at pageID, including overflow chains and nested buckets.
+func (tx *Tx) freeTree(pageID int) error {
+	node, err := tx.getNode(pageID)
+	if err != nil {
+		return fmt.Errorf("failed to read page %d: %w", pageID, err)
+	}
+
+	for i := uint16(0); i < node.getKeyCount(); i++ {
+		_, value := node.getLeafKeyValue(i)
+
+		switch {
+		case node.getType() == NodeBranch:
+			err = tx.freeTree(node.getChild(i))
+		case node.getFlags(i)&EntryFlagBucket != 0:
+			err = tx.freeTree(int(binary.LittleEndian.Uint64(value)))
+		case node.getFlags(i)&EntryFlagOverflow != 0:
+			err = tx.freeOverflow(value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	tx.freePage(pageID)
+	return nil
+}
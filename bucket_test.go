@@ -0,0 +1,163 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// bucketAt follows a slash separated path of nested bucket names from the root bucket.
+func bucketAt(tx *Tx, path string) (*Bucket, error) {
+	b := tx.rootBucket()
+	if path == "" {
+		return b, nil
+	}
+
+	for _, name := range strings.Split(path, "/") {
+		nested, err := b.Bucket([]byte(name))
+		if err != nil {
+			return nil, err
+		}
+		b = nested
+	}
+	return b, nil
+}
+
+// splitBucketPath splits a bucket path into the path of its parent and its own name.
+func splitBucketPath(path string) (string, string) {
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
+
+func TestNestedBuckets(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	rng := rand.New(rand.NewSource(6))
+	paths := []string{"a", "b", "c", "a/x", "a/y", "b/z", "a/x/deep"}
+	want := map[string]map[string]string{}
+	for round := 0; round < 40; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 60; i++ {
+				path := paths[rng.Intn(len(paths))]
+				parentPath, name := splitBucketPath(path)
+
+				if _, ok := want[path]; !ok {
+					if _, ok := want[parentPath]; parentPath != "" && !ok {
+						continue
+					}
+					parent, err := bucketAt(tx, parentPath)
+					if err != nil {
+						return err
+					}
+					if _, err := parent.CreateBucket([]byte(name)); err != nil {
+						return fmt.Errorf("create %s: %w", path, err)
+					}
+					want[path] = map[string]string{}
+					continue
+				}
+
+				if rng.Intn(40) == 0 {
+					parent, err := bucketAt(tx, parentPath)
+					if err != nil {
+						return err
+					}
+					if err := parent.DeleteBucket([]byte(name)); err != nil {
+						return fmt.Errorf("delete bucket %s: %w", path, err)
+					}
+					for p := range want {
+						if p == path || strings.HasPrefix(p, path+"/") {
+							delete(want, p)
+						}
+					}
+					continue
+				}
+
+				b, err := bucketAt(tx, path)
+				if err != nil {
+					return fmt.Errorf("bucket %s: %w", path, err)
+				}
+				k := fmt.Sprintf("k%03d", rng.Intn(300))
+				if _, ok := want[path][k]; ok && rng.Intn(2) == 0 {
+					if err := b.Delete([]byte(k)); err != nil {
+						return err
+					}
+					delete(want[path], k)
+					continue
+				}
+
+				v := strings.Repeat("v", []int{5, 300, 2000}[rng.Intn(3)])
+				if err := b.Put([]byte(k), []byte(v)); err != nil {
+					return err
+				}
+				want[path][k] = v
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPageAccounting(t, db)
+
+		db.View(func(tx *Tx) error {
+			for path, kv := range want {
+				b, err := bucketAt(tx, path)
+				if err != nil {
+					t.Fatalf("bucket %s: %v", path, err)
+				}
+
+				n := 0
+				c := b.Cursor()
+				for k, v := c.First(); k != nil; k, v = c.Next() {
+					// Nested buckets show up with a nil value
+					if v == nil {
+						continue
+					}
+					if kv[string(k)] != string(v) {
+						t.Fatalf("bucket %s: value of %s does not match", path, k)
+					}
+					n++
+				}
+				if err := c.Err(); err != nil {
+					t.Fatal(err)
+				}
+				if n != len(kv) {
+					t.Fatalf("bucket %s has %d keys, want %d", path, n, len(kv))
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func TestBucketErrors(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	err := db.Update(func(tx *Tx) error {
+		if _, err := tx.CreateBucket([]byte("b")); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket([]byte("b")); !errors.Is(err, ErrBucketExists) {
+			t.Errorf("CreateBucket of an existing bucket = %v, want ErrBucketExists", err)
+		}
+		if _, err := tx.Bucket([]byte("missing")); !errors.Is(err, ErrBucketNotFound) {
+			t.Errorf("Bucket of a missing bucket = %v, want ErrBucketNotFound", err)
+		}
+		if err := tx.DeleteBucket([]byte("missing")); !errors.Is(err, ErrBucketNotFound) {
+			t.Errorf("DeleteBucket of a missing bucket = %v, want ErrBucketNotFound", err)
+		}
+		if _, err := tx.Get([]byte("b")); !errors.Is(err, ErrIncompatibleValue) {
+			t.Errorf("Get of a bucket = %v, want ErrIncompatibleValue", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
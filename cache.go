@@ -0,0 +1,127 @@
+package gokv
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize is the memory budget of the page cache of a newly opened database, in bytes.
+const DefaultCacheSize = 4 << 20
+
+// CacheStats reports how well the page cache is doing.
+type CacheStats struct {
+	Hits    uint64 // reads served from the cache
+	Misses  uint64 // reads that went to disk
+	Pages   int    // pages currently cached
+	Size    int    // bytes currently cached
+	MaxSize int    // memory budget in bytes
+}
+
+// pageCache keeps recently read pages in memory, evicting the least recently used ones
+// once the memory budget is exhausted. Upper branch nodes are read by every lookup, so they stay resident.
+// It is shared by all transactions and guarded by its own mutex.
+type pageCache struct {
+	mu      sync.Mutex
+	maxSize int
+	size    int
+	lru     *list.List            // front is the most recently used page
+	pages   map[int]*list.Element // page ID -> element holding a *cachedPage
+	hits    uint64
+	misses  uint64
+}
+
+// cachedPage is a page buffer held by the cache.
+type cachedPage struct {
+	pageID int
+	data   []byte
+}
+
+// newPageCache creates an empty cache with the given memory budget in bytes.
+func newPageCache(maxSize int) *pageCache {
+	return &pageCache{
+		maxSize: maxSize,
+		lru:     list.New(),
+		pages:   make(map[int]*list.Element),
+	}
+}
+
+// get returns the cached buffer of a page and marks it as recently used.
+func (c *pageCache) get(pageID int) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.pages[pageID]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cachedPage).data, true
+}
+
+// put caches the buffer of a page read from disk, evicting old pages to stay within the budget.
+func (c *pageCache) put(pageID int, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(data) > c.maxSize {
+		return
+	}
+
+	if elem, ok := c.pages[pageID]; ok {
+		c.removeElement(elem)
+	}
+
+	c.pages[pageID] = c.lru.PushFront(&cachedPage{pageID: pageID, data: data})
+	c.size += len(data)
+	c.evict()
+}
+
+// remove drops a page from the cache, so the next read goes to disk.
+func (c *pageCache) remove(pageID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.pages[pageID]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// setMaxSize changes the memory budget, evicting pages if the cache is now over it.
+func (c *pageCache) setMaxSize(maxSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize = maxSize
+	c.evict()
+}
+
+// stats returns a snapshot of the cache counters.
+func (c *pageCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:    c.hits,
+		Misses:  c.misses,
+		Pages:   len(c.pages),
+		Size:    c.size,
+		MaxSize: c.maxSize,
+	}
+}
+
+// evict drops least recently used pages until the cache fits its budget. The caller must hold mu.
+func (c *pageCache) evict() {
+	for c.size > c.maxSize {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// removeElement drops a cached page. The caller must hold mu.
+func (c *pageCache) removeElement(elem *list.Element) {
+	page := c.lru.Remove(elem).(*cachedPage)
+	delete(c.pages, page.pageID)
+	c.size -= len(page.data)
+}
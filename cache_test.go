@@ -0,0 +1,53 @@
+package gokv
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPageCacheBudget(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+	err := db.Update(func(tx *Tx) error {
+		for i := 0; i < 3000; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("value")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db = openTestDB(t, path, nil)
+	defer db.Close()
+
+	const budget = 8 * DefaultPageSize
+	db.Pager.SetCacheSize(budget)
+	for i := 0; i < 3000; i += 7 {
+		db.View(func(tx *Tx) error {
+			v, err := tx.Get([]byte(fmt.Sprintf("k%05d", i)))
+			if err != nil || string(v) != "value" {
+				t.Fatalf("Get(k%05d) = %q, %v", i, v, err)
+			}
+			return nil
+		})
+	}
+
+	st := db.Pager.CacheStats()
+	if st.Size > budget || st.MaxSize != budget {
+		t.Fatalf("cache holds %d bytes with a budget of %d, stats %+v", st.Size, budget, st)
+	}
+	if st.Hits == 0 || st.Misses == 0 {
+		t.Fatalf("expected both hits and misses, stats %+v", st)
+	}
+
+	db.Pager.SetCacheSize(0)
+	if st := db.Pager.CacheStats(); st.Pages != 0 || st.Size != 0 {
+		t.Fatalf("cache not emptied by a zero budget, stats %+v", st)
+	}
+}
@@ -0,0 +1,251 @@
+package gokv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Check walks every page the transaction can reach and returns every problem it finds, instead of
+// stopping at the first one. It checks node types and checksums, offset tables, key order within and
+// across nodes, branch separators, overflow chains, and that each page is either reachable or on the
+// freelist, but not both. Leaked pages are not reported when part of a tree can't be read.
+// Pages are only matched against the freelist when the transaction has not modified anything,
+// so Check is best run in a read transaction. Problems are *CorruptionError values, or ErrTxClosed alone
+// if the transaction is finished.
+func (tx *Tx) Check() []error {
+	if tx.db == nil {
+		return []error{ErrTxClosed}
+	}
+
+	c := &checker{
+		tx:        tx,
+		pageCount: int(tx.meta.PageCount),
+		reachable: make(map[int]string),
+	}
+
+	// A write transaction may have allocated pages past the committed page count
+	if tx.writable {
+		c.pageCount = tx.db.Pager.numPages
+	}
+
+	c.reachable[MetaPageID0] = "meta page"
+	c.reachable[MetaPageID1] = "meta page"
+
+	c.checkTree(tx.root)
+
+	if len(tx.dirtyNodes) == 0 && len(tx.freed) == 0 {
+		c.checkFreelist()
+	}
+
+	return c.errs
+}
+
+// checker collects the problems found while walking a transaction's pages.
+type checker struct {
+	tx        *Tx
+	pageCount int
+	reachable map[int]string // page ID -> what references it
+	errs      []error
+
+	// Set when part of a tree couldn't be walked; the pages below it can't be told apart from leaked pages
+	incomplete bool
+}
+
+// errorf records a problem with a page.
+func (c *checker) errorf(pageID int, format string, args ...any) {
+	c.errs = append(c.errs, &CorruptionError{PageID: pageID, Offset: -1, Reason: fmt.Sprintf(format, args...)})
+}
+
+// unreadable records a page that couldn't be read, keeping corruption errors as they are.
+func (c *checker) unreadable(pageID int, err error) {
+	var corruption *CorruptionError
+	if errors.As(err, &corruption) {
+		c.errs = append(c.errs, corruption)
+		return
+	}
+	c.errorf(pageID, "unreadable: %v", err)
+}
+
+// visit marks a page as reachable. It reports false if the page can't be read as part of the
+// database: it's out of bounds, or it is already referenced from somewhere else.
+func (c *checker) visit(pageID int, what string) bool {
+	if pageID <= MetaPageID1 || pageID >= c.pageCount {
+		c.errorf(pageID, "%s is outside the data pages 2..%d", what, c.pageCount-1)
+		return false
+	}
+
+	if prev, ok := c.reachable[pageID]; ok {
+		c.errorf(pageID, "%s is already referenced as %s", what, prev)
+		return false
+	}
+
+	c.reachable[pageID] = what
+	return true
+}
+
+// checkTree checks the tree rooted at pageID, including the buckets nested in it.
+func (c *checker) checkTree(root int) {
+	leafDepth := 0
+	c.checkNode(root, nil, nil, 1, &leafDepth)
+}
+
+// checkNode checks the node at pageID and its subtree. Every key in the subtree must be at least
+// lower and below upper; a nil bound is open. All leaves of a tree must be at the same depth.
+func (c *checker) checkNode(pageID int, lower, upper []byte, depth int, leafDepth *int) {
+	if !c.visit(pageID, "tree page") {
+		return
+	}
+
+	// Reading the node checks its type and layout, so its entries can be read safely from here on
+	node, err := c.tx.getNode(pageID)
+	if err != nil {
+		c.unreadable(pageID, err)
+		c.incomplete = true
+		return
+	}
+
+	nodeType := node.getType()
+	count := node.getKeyCount()
+
+	// Keys must be strictly increasing and within the bounds set by the parent
+	for i := uint16(0); i < count; i++ {
+		key, _ := node.getLeafKeyValue(i)
+
+		// Only the first key of a branch may be cleared to route smaller keys into its first child
+		if nodeType == NodeBranch && i == 0 && len(key) == 0 {
+			continue
+		}
+
+		if i > 0 {
+			prev, _ := node.getLeafKeyValue(i - 1)
+			if bytes.Compare(prev, key) >= 0 {
+				c.errorf(pageID, "key %d %q is not after key %d %q", i, key, i-1, prev)
+			}
+		}
+		if lower != nil && bytes.Compare(key, lower) < 0 {
+			c.errorf(pageID, "key %d %q is before the parent separator %q", i, key, lower)
+		}
+		if upper != nil && bytes.Compare(key, upper) >= 0 {
+			c.errorf(pageID, "key %d %q is not before the next parent separator %q", i, key, upper)
+		}
+	}
+
+	if nodeType == NodeBranch {
+		for i := uint16(0); i < count; i++ {
+			key, _ := node.getLeafKeyValue(i)
+
+			childLower, childUpper := lower, upper
+			if len(key) > 0 {
+				childLower = key
+			}
+			if i+1 < count {
+				childUpper, _ = node.getLeafKeyValue(i + 1)
+			}
+
+			c.checkNode(node.getChild(i), childLower, childUpper, depth+1, leafDepth)
+		}
+		return
+	}
+
+	if *leafDepth == 0 {
+		*leafDepth = depth
+	} else if depth != *leafDepth {
+		c.errorf(pageID, "leaf is at depth %d, other leaves of the tree are at depth %d", depth, *leafDepth)
+	}
+
+	for i := uint16(0); i < count; i++ {
+		key, value := node.getLeafKeyValue(i)
+		flags := node.getFlags(i)
+
+		switch flags {
+		case 0:
+			if len(value) > maxInlineValueSize(c.tx.pageSize()) {
+				c.errorf(pageID, "key %q has a %d byte inline value", key, len(value))
+			}
+
+		case EntryFlagBucket:
+			c.checkTree(int(binary.LittleEndian.Uint64(value)))
+
+		case EntryFlagOverflow:
+			c.checkOverflow(pageID, key, value)
+		}
+	}
+}
+
+// checkOverflow checks the overflow chain a leaf entry refers to.
+func (c *checker) checkOverflow(leafID int, key []byte, ref []byte) {
+	pageID, length, err := decodeOverflowRef(ref)
+	if err != nil {
+		c.errorf(leafID, "key %q: %v", key, err)
+		return
+	}
+
+	read := 0
+	for read < length {
+		if pageID == 0 {
+			c.errorf(leafID, "overflow chain of key %q ends after %d of %d bytes", key, read, length)
+			return
+		}
+		if !c.visit(pageID, fmt.Sprintf("overflow page of key %q", key)) {
+			return
+		}
+
+		node, err := c.tx.getOverflowNode(pageID)
+		if err != nil {
+			c.unreadable(pageID, err)
+			return
+		}
+
+		chunkLen := int(node.getKeyCount())
+		if chunkLen == 0 || OverflowHeaderSize+chunkLen > len(node.data) || read+chunkLen > length {
+			c.errorf(pageID, "overflow page of key %q holds an invalid %d bytes", key, chunkLen)
+			return
+		}
+
+		read += chunkLen
+		pageID = node.getOverflowNext()
+	}
+
+	if pageID != 0 {
+		c.errorf(leafID, "overflow chain of key %q continues past its %d bytes", key, length)
+	}
+}
+
+// checkFreelist checks the freelist of the transaction's snapshot, then that every page is either
+// reachable or free, but not both.
+func (c *checker) checkFreelist() {
+	freePages, listPages, err := c.tx.db.Pager.loadFreelist(int(c.tx.meta.FreeList), c.pageCount)
+	if err != nil {
+		c.errorf(int(c.tx.meta.FreeList), "unreadable freelist: %v", err)
+		return
+	}
+
+	for _, pageID := range listPages {
+		c.visit(pageID, "freelist page")
+	}
+
+	free := make(map[int]bool, len(freePages))
+	for _, pageID := range freePages {
+		switch {
+		case pageID <= MetaPageID1 || pageID >= c.pageCount:
+			c.errorf(pageID, "free page is outside the data pages 2..%d", c.pageCount-1)
+		case free[pageID]:
+			c.errorf(pageID, "page is on the freelist twice")
+		case c.reachable[pageID] != "":
+			c.errorf(pageID, "%s is also on the freelist", c.reachable[pageID])
+		}
+		free[pageID] = true
+	}
+
+	if c.incomplete {
+		return
+	}
+
+	for pageID := MetaPageID1 + 1; pageID < c.pageCount; pageID++ {
+		if c.reachable[pageID] == "" && !free[pageID] {
+			c.errorf(pageID, "page is unreachable and not on the freelist")
+		}
+	}
+}
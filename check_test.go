@@ -0,0 +1,151 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+// corruptionAt returns the reason of the corruption reported for pageID, or "" if there is none.
+func corruptionAt(errs []error, pageID int) string {
+	for _, err := range errs {
+		var ce *CorruptionError
+		if errors.As(err, &ce) && ce.PageID == pageID {
+			return ce.Reason
+		}
+	}
+	return ""
+}
+
+func TestCheck(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 60; round++ {
+		err := db.Update(func(tx *Tx) error {
+			b, err := tx.Bucket([]byte("bkt"))
+			if errors.Is(err, ErrBucketNotFound) {
+				b, err = tx.CreateBucket([]byte("bkt"))
+			}
+			if err != nil {
+				return err
+			}
+
+			for i := 0; i < 400; i++ {
+				k := []byte(fmt.Sprintf("%x", rng.Intn(3000)))
+				target := tx.rootBucket()
+				if rng.Intn(3) == 0 {
+					target = b
+				}
+				if rng.Intn(2) == 0 {
+					if err := target.Delete(k); err != nil && !errors.Is(err, ErrKeyNotFound) {
+						return err
+					}
+				} else if err := target.Put(k, make([]byte, rng.Intn(2500))); err != nil {
+					return err
+				}
+			}
+
+			if errs := tx.Check(); len(errs) > 0 {
+				t.Fatalf("round %d, in the write transaction: %v", round, errs)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db.View(func(tx *Tx) error {
+			if errs := tx.Check(); len(errs) > 0 {
+				t.Fatalf("round %d: %v", round, errs)
+			}
+			return nil
+		})
+	}
+
+	root := db.Root
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Flip a byte in the root page, which fails its checksum
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte{0x42}, int64(root*DefaultPageSize+100)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	db = openTestDB(t, path, nil)
+	defer db.Close()
+	db.View(func(tx *Tx) error {
+		if errs := tx.Check(); corruptionAt(errs, root) == "" {
+			t.Fatalf("Check did not report the corrupted root page %d: %v", root, errs)
+		}
+		return nil
+	})
+}
+
+func TestCheckKeyOrderAndLeakedPages(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	err := db.Update(func(tx *Tx) error {
+		for i := 0; i < 2000; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Skip a page, so the next commit leaves it neither reachable nor free
+	leaked := db.Pager.numPages
+	db.Pager.numPages++
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("x"), []byte("v")) }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Swap the first two keys of a leaf, keeping its checksum valid
+	var leafID int
+	db.View(func(tx *Tx) error {
+		root, err := tx.getNode(tx.root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leafID = root.getChild(1)
+
+		leaf, err := tx.getNode(leafID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pairs := leaf.getEntries()
+		pairs[0], pairs[1] = pairs[1], pairs[0]
+		swapped, err := newNodeFromEntries(tx.pageSize(), NodeLeaf, pairs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		swapped.setChecksum()
+		return db.Pager.Write(leafID, swapped.data)
+	})
+
+	db.View(func(tx *Tx) error {
+		errs := tx.Check()
+		if reason := corruptionAt(errs, leafID); !strings.Contains(reason, "is not after") {
+			t.Errorf("Check did not report the swapped keys in page %d: %v", leafID, errs)
+		}
+		if reason := corruptionAt(errs, leaked); !strings.Contains(reason, "unreachable") {
+			t.Errorf("Check did not report the leaked page %d: %v", leaked, errs)
+		}
+		return nil
+	})
+}
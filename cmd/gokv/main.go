@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"gokv"
 	"os"
@@ -9,11 +10,35 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "compact":
+			err = compact(os.Args[2:])
+		case "check":
+			err = check(os.Args[2:])
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown command %q. Commands: compact, check\n", os.Args[1])
+			os.Exit(2)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	repl()
+}
+
+// repl runs the interactive shell on my.db.
+func repl() {
 	db, err := gokv.Open("my.db")
 	if err != nil {
 		panic(err)
 	}
-	defer db.Pager.Close()
+	defer db.Close()
 
 	fmt.Println("Welcome to GoKV! Type 'help' for commands.")
 	scanner := bufio.NewScanner(os.Stdin)
@@ -63,14 +88,131 @@ func main() {
 				fmt.Printf("Error: %v\n", err)
 			}
 
+		case "del":
+			if len(parts) != 2 {
+				fmt.Println("Usage: del <key>")
+				continue
+			}
+			err := db.Update(func(tx *gokv.Tx) error {
+				return tx.Delete([]byte(parts[1]))
+			})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			} else {
+				fmt.Println("OK")
+			}
+
+		case "scan":
+			if len(parts) > 2 {
+				fmt.Println("Usage: scan [start]")
+				continue
+			}
+			err := db.View(func(tx *gokv.Tx) error {
+				c := tx.Cursor()
+				k, v := c.First()
+				if len(parts) == 2 {
+					k, v = c.Seek([]byte(parts[1]))
+				}
+				for ; k != nil; k, v = c.Next() {
+					fmt.Printf("%s = %s\n", k, v)
+				}
+				return c.Err()
+			})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+
 		case "exit", "quit":
 			return
 
 		case "help":
-			fmt.Println("Commands: put <k> <v>, get <k>, exit")
+			fmt.Println("Commands: put <k> <v>, get <k>, del <k>, scan [start], exit")
 
 		default:
 			fmt.Println("Unknown command")
 		}
 	}
 }
+
+// compact writes a compacted copy of a database file, and with -swap replaces the original with it.
+// Swapping is offline only: a process that has the old file open, or is waiting for its lock, keeps using
+// the old file after the rename, and everything it commits from then on is lost.
+func compact(args []string) error {
+	flags := flag.NewFlagSet("compact", flag.ExitOnError)
+	swap := flags.Bool("swap", false, "replace <src> with the compacted copy once it is written; only while no other process uses <src>")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gokv compact [-swap] <src> <dst>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	src, dst := flags.Arg(0), flags.Arg(1)
+
+	// A swap takes the exclusive lock, so it fails right away if another process has the file open.
+	// That can't stop a process from opening the old file and waiting for the lock in the meantime.
+	db, err := gokv.OpenWithOptions(src, &gokv.Options{ReadOnly: !*swap})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = db.CompactTo(dst)
+	if err != nil {
+		return err
+	}
+
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Compacted %s (%d bytes) into %s (%d bytes)\n", src, srcInfo.Size(), dst, dstInfo.Size())
+
+	if *swap {
+		// Rename replaces the original atomically, so readers see either the old or the new file
+		err = os.Rename(dst, src)
+		if err != nil {
+			return fmt.Errorf("failed to swap in compacted file: %w", err)
+		}
+		fmt.Printf("Replaced %s with the compacted copy\n", src)
+	}
+
+	return nil
+}
+
+// check walks every page of a database file and prints every problem it finds.
+func check(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: gokv check <path>")
+		os.Exit(2)
+	}
+
+	db, err := gokv.OpenWithOptions(args[0], &gokv.Options{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var problems []error
+	db.View(func(tx *gokv.Tx) error {
+		problems = tx.Check()
+		return nil
+	})
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("check found %d problem(s)", len(problems))
+	}
+
+	fmt.Println("OK")
+	return nil
+}
@@ -0,0 +1,230 @@
+package gokv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// CompactTo writes a compacted copy of the database to a new file at path, from a read transaction,
+// so writers carry on while it runs. The copy holds only the live data: every tree is rebuilt bottom-up
+// with full leaves and branches laid out in key order, and the file has no free pages.
+// The copy is only valid once CompactTo returns nil; on error the partial file is removed.
+func (db *DB) CompactTo(path string) error {
+	return db.View(func(tx *Tx) error {
+		return tx.compactTo(path)
+	})
+}
+
+// compactor writes a compacted copy of the trees a transaction sees straight into a new file.
+// Pages are handed out in order, so every page is written exactly once and never read back.
+type compactor struct {
+	tx  *Tx
+	dst *Pager
+	err error // first error writing a page
+}
+
+// compactTo writes a compacted copy of the transaction's snapshot to a new file at path.
+func (tx *Tx) compactTo(path string) error {
+	// The copy gets the permissions of the original file, if there is one
+	mode := DefaultOptions.FileMode
+	if file := tx.db.Pager.file; file != nil {
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		mode = info.Mode().Perm()
+	}
+
+	// Never write over an existing file. Creating it exclusively also means that on failure,
+	// the file removed is always the one this call created.
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	dst, err := newPager(&FileStorage{File: file}, &Options{PageSize: tx.pageSize()})
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	err = (&compactor{tx: tx, dst: dst}).run()
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// run copies the trees after the two meta pages, then writes the meta pages pointing to the new root.
+// Until then the file has no valid meta page, so an interrupted copy can't be opened by mistake.
+func (c *compactor) run() error {
+	c.dst.numPages = MetaPageID1 + 1
+
+	root, err := c.copyTree(c.tx.root)
+	if err != nil {
+		return err
+	}
+
+	meta := &Meta{
+		Magic:     DBMagic,
+		Version:   MetaVersion,
+		Root:      uint32(root),
+		PageCount: uint32(c.dst.numPages),
+		PageSize:  uint32(c.dst.pageSize),
+	}
+
+	for txID := uint64(0); txID < 2; txID++ {
+		meta.TxID = txID
+		buf := make([]byte, c.dst.pageSize)
+		meta.serialize(buf)
+
+		err = c.dst.Write(meta.pageID(), buf)
+		if err != nil {
+			return fmt.Errorf("failed to write meta page: %w", err)
+		}
+	}
+
+	return c.dst.Sync()
+}
+
+// copyTree copies the tree rooted at pageID and returns the page of its new root.
+// Leaves are filled before moving on to the next one, then each branch level is built the same way
+// from the first keys of the level below.
+func (c *compactor) copyTree(pageID int) (int, error) {
+	var leaf, level []kvPair
+
+	err := c.walk(pageID, func(pair kvPair) error {
+		if entriesSize(append(leaf, pair)) > c.dst.pageSize {
+			level = append(level, c.writeNode(NodeLeaf, leaf))
+			leaf = nil
+		}
+		leaf = append(leaf, pair)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// An empty tree still needs its empty root leaf
+	if len(leaf) > 0 || len(level) == 0 {
+		level = append(level, c.writeNode(NodeLeaf, leaf))
+	}
+
+	for len(level) > 1 {
+		var branch, next []kvPair
+		for _, pair := range level {
+			if entriesSize(append(branch, pair)) > c.dst.pageSize {
+				next = append(next, c.writeNode(NodeBranch, branch))
+				branch = nil
+			}
+			branch = append(branch, pair)
+		}
+		level = append(next, c.writeNode(NodeBranch, branch))
+	}
+
+	if c.err != nil {
+		return 0, c.err
+	}
+	return int(binary.LittleEndian.Uint64(level[0].val)), nil
+}
+
+// walk calls fn for every leaf entry of the tree rooted at pageID in key order, after copying the
+// overflow chain or nested bucket the entry points to, so the entry already refers to the new pages.
+func (c *compactor) walk(pageID int, fn func(kvPair) error) error {
+	node, err := c.tx.getNode(pageID)
+	if err != nil {
+		return fmt.Errorf("failed to read page %d: %w", pageID, err)
+	}
+
+	for _, pair := range node.getEntries() {
+		switch {
+		case node.getType() == NodeBranch:
+			err = c.walk(int(binary.LittleEndian.Uint64(pair.val)), fn)
+			if err != nil {
+				return err
+			}
+			continue
+
+		case pair.flags&EntryFlagBucket != 0:
+			root, err := c.copyTree(int(binary.LittleEndian.Uint64(pair.val)))
+			if err != nil {
+				return err
+			}
+			pair.val = encodePageID(root)
+
+		case pair.flags&EntryFlagOverflow != 0:
+			pair.val, err = c.copyOverflow(pair.val)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := fn(pair); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyOverflow copies a spilled value into a new chain of consecutive pages and returns its reference.
+func (c *compactor) copyOverflow(ref []byte) ([]byte, error) {
+	value, err := c.tx.readOverflow(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkSize := c.dst.pageSize - OverflowHeaderSize
+	first := c.dst.numPages
+
+	for start := 0; start < len(value); start += chunkSize {
+		end := min(start+chunkSize, len(value))
+		pageID := c.dst.GetFreePage()
+
+		next := 0
+		if end < len(value) {
+			next = pageID + 1
+		}
+
+		c.write(pageID, newOverflowNode(c.dst.pageSize, value[start:end], next))
+	}
+
+	return encodeOverflowRef(first, len(value)), c.err
+}
+
+// writeNode writes a node holding the entries to the next page and returns the branch entry pointing to it.
+func (c *compactor) writeNode(nodeType uint16, pairs []kvPair) kvPair {
+	pageID := c.dst.GetFreePage()
+
+	node, err := newNodeFromEntries(c.dst.pageSize, nodeType, pairs)
+	if err == nil {
+		c.write(pageID, node)
+	} else if c.err == nil {
+		c.err = fmt.Errorf("failed to build page %d: %w", pageID, err)
+	}
+
+	var firstKey []byte
+	if len(pairs) > 0 {
+		firstKey = pairs[0].key
+	}
+	return kvPair{key: firstKey, val: encodePageID(pageID)}
+}
+
+// write checksums a node and writes it to its page, remembering the first error.
+func (c *compactor) write(pageID int, node *Node) {
+	if c.err != nil {
+		return
+	}
+
+	node.setChecksum()
+	err := c.dst.Write(pageID, node.data)
+	if err != nil {
+		c.err = fmt.Errorf("failed to write page %d: %w", pageID, err)
+	}
+}
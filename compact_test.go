@@ -0,0 +1,154 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompactTo(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.db")
+	db := openTestDB(t, path, nil)
+	defer db.Close()
+
+	want := map[string]string{}
+	for round := 0; round < 10; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 1000; i++ {
+				k := fmt.Sprintf("k%05d", (i*7919+round)%4000)
+				if (i+round)%3 == 0 {
+					if tx.Delete([]byte(k)) == nil {
+						delete(want, k)
+					}
+					continue
+				}
+
+				v := strings.Repeat("x", (i*31+round)%3000)
+				if err := tx.Put([]byte(k), []byte(v)); err != nil {
+					return err
+				}
+				want[k] = v
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := db.Update(func(tx *Tx) error {
+		b, err := tx.CreateBucket([]byte("bkt"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 500; i++ {
+			if err := b.Put([]byte(fmt.Sprintf("b%04d", i)), []byte(strings.Repeat("y", i*13))); err != nil {
+				return err
+			}
+		}
+		nested, err := b.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		if err := nested.Put([]byte("deep"), []byte("value")); err != nil {
+			return err
+		}
+		_, err = tx.CreateBucket([]byte("empty"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "compacted.db")
+	if err := db.CompactTo(dst); err != nil {
+		t.Fatal(err)
+	}
+	// A second copy fails without touching the first
+	if err := db.CompactTo(dst); err == nil {
+		t.Fatal("CompactTo overwrote an existing file")
+	}
+
+	src, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compacted, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compacted.Size() >= src.Size() {
+		t.Errorf("compacted file has %d bytes, the original %d", compacted.Size(), src.Size())
+	}
+
+	c := openTestDB(t, dst, nil)
+	defer c.Close()
+	checkPageAccounting(t, c)
+
+	c.View(func(tx *Tx) error {
+		n := 0
+		cur := tx.Cursor()
+		for k, v := cur.First(); k != nil; k, v = cur.Next() {
+			if v == nil {
+				continue
+			}
+			if want[string(k)] != string(v) {
+				t.Fatalf("value of %s does not match", k)
+			}
+			n++
+		}
+		if err := cur.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if n != len(want) {
+			t.Fatalf("compacted database has %d keys, want %d", n, len(want))
+		}
+
+		b, err := tx.Bucket([]byte("bkt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 500; i++ {
+			v, err := b.Get([]byte(fmt.Sprintf("b%04d", i)))
+			if err != nil || string(v) != strings.Repeat("y", i*13) {
+				t.Fatalf("bucket value b%04d: %v", i, err)
+			}
+		}
+		nested, err := bucketAt(tx, "bkt/nested")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := nested.Get([]byte("deep")); err != nil || string(got) != "value" {
+			t.Fatalf("nested bucket value = %q, %v", got, err)
+		}
+		if _, err := tx.Bucket([]byte("empty")); err != nil {
+			t.Fatal(err)
+		}
+		return nil
+	})
+
+	// The compacted database takes new writes like any other
+	err = c.Update(func(tx *Tx) error {
+		for i := 0; i < 2000; i++ {
+			k := []byte(fmt.Sprintf("k%05d", i))
+			if i%2 == 0 {
+				if err := tx.Delete(k); err != nil && !errors.Is(err, ErrKeyNotFound) {
+					return err
+				}
+				continue
+			}
+			if err := tx.Put(k, []byte("new")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPageAccounting(t, c)
+}
@@ -0,0 +1,273 @@
+package gokv
+
+import "fmt"
+
+// Cursor iterates over the key-value pairs of a transaction in key order, in both directions.
+// Keys and values returned by a cursor are only valid for the life of the transaction,
+// and a cursor should not be used after the tree is modified.
+type Cursor struct {
+	tx     *Tx
+	bucket *Bucket
+	stack  []elemRef
+	err    error
+}
+
+// elemRef points at an entry of a node on the path from the root to the current leaf.
+type elemRef struct {
+	pageID int
+	node   *Node
+	index  int
+}
+
+// Cursor returns a new cursor over the top level of the database. It is not positioned
+// until First, Last or Seek is called.
+func (tx *Tx) Cursor() *Cursor {
+	return tx.rootBucket().Cursor()
+}
+
+// First moves the cursor to the first key and returns its key and value.
+// Returns a nil key if the tree is empty.
+func (c *Cursor) First() ([]byte, []byte) {
+	c.stack = c.stack[:0]
+	root, ok := c.rootPage()
+	if !ok || !c.push(root, 0) || !c.first() {
+		return nil, nil
+	}
+
+	// An empty leaf has nothing to return, move on to the next one
+	if c.stack[len(c.stack)-1].node.getKeyCount() == 0 {
+		return c.next()
+	}
+	return c.keyValue()
+}
+
+// Last moves the cursor to the last key and returns its key and value.
+// Returns a nil key if the tree is empty.
+func (c *Cursor) Last() ([]byte, []byte) {
+	c.stack = c.stack[:0]
+	root, ok := c.rootPage()
+	if !ok || !c.pushLast(root) || !c.last() {
+		return nil, nil
+	}
+
+	if c.stack[len(c.stack)-1].node.getKeyCount() == 0 {
+		return c.prev()
+	}
+	return c.keyValue()
+}
+
+// Next moves the cursor to the next key and returns its key and value.
+// Returns a nil key once the cursor moves past the last key.
+func (c *Cursor) Next() ([]byte, []byte) {
+	if !c.open() {
+		return nil, nil
+	}
+	return c.next()
+}
+
+// Prev moves the cursor to the previous key and returns its key and value.
+// Returns a nil key once the cursor moves before the first key.
+func (c *Cursor) Prev() ([]byte, []byte) {
+	if !c.open() {
+		return nil, nil
+	}
+	return c.prev()
+}
+
+// Seek moves the cursor to the given key, or to the next key after it if it doesn't exist.
+// Returns a nil key if there are no keys at or after seek.
+func (c *Cursor) Seek(seek []byte) ([]byte, []byte) {
+	c.stack = c.stack[:0]
+
+	pageID, ok := c.rootPage()
+	if !ok {
+		return nil, nil
+	}
+
+	for {
+		if !c.push(pageID, 0) {
+			return nil, nil
+		}
+
+		ref := &c.stack[len(c.stack)-1]
+		if ref.node.getType() == NodeLeaf {
+			index, _ := ref.node.findKeyInNode(seek)
+			ref.index = int(index)
+			break
+		}
+
+		index := ref.node.findChildIndex(seek)
+		ref.index = int(index)
+		pageID = ref.node.getChild(index)
+	}
+
+	// The seek key is past the end of this leaf, so the answer is in the next one
+	ref := c.stack[len(c.stack)-1]
+	if ref.index >= int(ref.node.getKeyCount()) {
+		return c.next()
+	}
+	return c.keyValue()
+}
+
+// Err returns the first error the cursor ran into while reading pages, if any.
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// next moves to the next leaf entry, climbing up the stack until a node has an entry to the right.
+func (c *Cursor) next() ([]byte, []byte) {
+	for {
+		i := len(c.stack) - 1
+		for ; i >= 0; i-- {
+			ref := &c.stack[i]
+			if ref.index < int(ref.node.getKeyCount())-1 {
+				ref.index++
+				break
+			}
+		}
+
+		// Already at the last entry of the tree
+		if i < 0 {
+			return nil, nil
+		}
+
+		c.stack = c.stack[:i+1]
+		if !c.first() {
+			return nil, nil
+		}
+
+		if c.stack[len(c.stack)-1].node.getKeyCount() == 0 {
+			continue
+		}
+		return c.keyValue()
+	}
+}
+
+// prev moves to the previous leaf entry, climbing up the stack until a node has an entry to the left.
+func (c *Cursor) prev() ([]byte, []byte) {
+	for {
+		i := len(c.stack) - 1
+		for ; i >= 0; i-- {
+			ref := &c.stack[i]
+			if ref.index > 0 {
+				ref.index--
+				break
+			}
+		}
+
+		// Already at the first entry of the tree
+		if i < 0 {
+			return nil, nil
+		}
+
+		c.stack = c.stack[:i+1]
+		if !c.last() {
+			return nil, nil
+		}
+
+		if c.stack[len(c.stack)-1].node.getKeyCount() == 0 {
+			continue
+		}
+		return c.keyValue()
+	}
+}
+
+// first descends from the top of the stack to the leftmost leaf below it.
+func (c *Cursor) first() bool {
+	for {
+		ref := c.stack[len(c.stack)-1]
+		if ref.node.getType() == NodeLeaf {
+			return true
+		}
+
+		if !c.push(ref.node.getChild(uint16(ref.index)), 0) {
+			return false
+		}
+	}
+}
+
+// last descends from the top of the stack to the rightmost leaf below it.
+func (c *Cursor) last() bool {
+	for {
+		ref := c.stack[len(c.stack)-1]
+		if ref.node.getType() == NodeLeaf {
+			return true
+		}
+
+		if !c.pushLast(ref.node.getChild(uint16(ref.index))) {
+			return false
+		}
+	}
+}
+
+// open reports whether the cursor's transaction is still open. The nodes on the stack may point into
+// a mapping that is gone once the transaction has finished, so they must not be touched after that.
+func (c *Cursor) open() bool {
+	if c.tx.db == nil {
+		c.err = ErrTxClosed
+		return false
+	}
+	return true
+}
+
+// rootPage looks up the root page of the cursor's bucket.
+func (c *Cursor) rootPage() (int, bool) {
+	root, err := c.bucket.rootPage()
+	if err != nil {
+		c.err = err
+		return 0, false
+	}
+	return root, true
+}
+
+// push reads a page and puts it on top of the stack, positioned at the given index.
+func (c *Cursor) push(pageID int, index int) bool {
+	node, err := c.tx.getNode(pageID)
+	if err != nil {
+		c.err = fmt.Errorf("failed to read page %d: %w", pageID, err)
+		return false
+	}
+
+	c.stack = append(c.stack, elemRef{pageID: pageID, node: node, index: index})
+	return true
+}
+
+// pushLast reads a page and puts it on top of the stack, positioned at its last entry.
+func (c *Cursor) pushLast(pageID int) bool {
+	if !c.push(pageID, 0) {
+		return false
+	}
+
+	ref := &c.stack[len(c.stack)-1]
+	ref.index = int(ref.node.getKeyCount()) - 1
+	return true
+}
+
+// keyValue returns the key and value of the entry the cursor points at.
+func (c *Cursor) keyValue() ([]byte, []byte) {
+	if len(c.stack) == 0 {
+		return nil, nil
+	}
+
+	ref := c.stack[len(c.stack)-1]
+	if ref.index < 0 || ref.index >= int(ref.node.getKeyCount()) {
+		return nil, nil
+	}
+
+	key, value := ref.node.getLeafKeyValue(uint16(ref.index))
+
+	if ref.node.getFlags(uint16(ref.index))&EntryFlagBucket != 0 {
+		return key, nil
+	}
+
+	if ref.node.getFlags(uint16(ref.index))&EntryFlagOverflow != 0 {
+		var err error
+		value, err = c.tx.readOverflow(value)
+		if err != nil {
+			c.err = fmt.Errorf("failed to read value of key %q: %w", key, err)
+			return nil, nil
+		}
+	}
+
+	return key, value
+}
@@ -0,0 +1,104 @@
+package gokv
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	rng := rand.New(rand.NewSource(2))
+	want := map[string]string{}
+
+	check := func(tx *Tx) {
+		t.Helper()
+
+		keys := make([]string, 0, len(want))
+		for k := range want {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		c := tx.Cursor()
+		i := 0
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if i >= len(keys) || string(k) != keys[i] || string(v) != want[keys[i]] {
+				t.Fatalf("forward scan: unexpected key %q at %d", k, i)
+			}
+			i++
+		}
+		if i != len(keys) {
+			t.Fatalf("forward scan: got %d keys, want %d", i, len(keys))
+		}
+
+		i = len(keys) - 1
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			if i < 0 || string(k) != keys[i] {
+				t.Fatalf("backward scan: unexpected key %q at %d", k, i)
+			}
+			i--
+		}
+		if i != -1 {
+			t.Fatalf("backward scan: %d keys not visited", i+1)
+		}
+
+		for j := 0; j < 50; j++ {
+			seek := fmt.Sprintf("key-%05d", rng.Intn(3100))
+			k, _ := c.Seek([]byte(seek))
+			idx := sort.SearchStrings(keys, seek)
+			if idx == len(keys) {
+				if k != nil {
+					t.Fatalf("Seek(%s) = %q, want nil", seek, k)
+				}
+			} else if string(k) != keys[idx] {
+				t.Fatalf("Seek(%s) = %q, want %s", seek, k, keys[idx])
+			}
+		}
+
+		if err := c.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db.View(func(tx *Tx) error {
+		check(tx)
+		return nil
+	})
+
+	for round := 0; round < 15; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 300; i++ {
+				k := fmt.Sprintf("key-%05d", rng.Intn(3000))
+				if _, ok := want[k]; ok {
+					if rng.Intn(2) == 0 {
+						if err := tx.Delete([]byte(k)); err != nil {
+							return err
+						}
+						delete(want, k)
+					}
+					continue
+				}
+
+				v := fmt.Sprintf("v%d", rng.Intn(1e6))
+				if err := tx.Put([]byte(k), []byte(v)); err != nil {
+					return err
+				}
+				want[k] = v
+			}
+			check(tx)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db.View(func(tx *Tx) error {
+			check(tx)
+			return nil
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // DB represents a B-tree database instance with a pager for disk I/O and a root page ID.
@@ -11,101 +12,350 @@ type DB struct {
 	Pager *Pager
 	Root  int
 	Meta  *Meta
-	mu    sync.RWMutex
+	mu    sync.Mutex // held by the open write transaction, if any
+
+	// metaMu guards Root, Meta, readers and closed, which read transactions access concurrently with the writer
+	metaMu  sync.RWMutex
+	readers map[*Tx]struct{}
+	closed  bool
+
+	readOnly bool
+	noSync   bool // skip fsync on commit
+
+	// MaxBatchSize and MaxBatchDelay control how Batch groups functions into one transaction.
+	// They can be changed before the database is used.
+	MaxBatchSize  int
+	MaxBatchDelay time.Duration
+
+	batchMu sync.Mutex
+	batch   *writeGroup // the group still accepting functions, if any
+
+	stats dbStats
 }
 
-// Return a new Tx struct
+// Begin starts a transaction pinned to the meta and root of the last commit. It must be finished
+// with Commit or Rollback. Only one write transaction is open at a time: a writer holds the write lock
+// until it finishes, and Begin(true) blocks until then. Read transactions are registered so the pages
+// they can reach are not reused until they finish.
 func (db *DB) Begin(writable bool) (*Tx, error) {
-	return &Tx{
+	if writable && db.readOnly {
+		return nil, fmt.Errorf("cannot begin write transaction: %w", ErrDatabaseReadOnly)
+	}
+
+	if writable {
+		db.mu.Lock()
+		db.releaseFreedPages()
+	}
+
+	db.metaMu.Lock()
+	defer db.metaMu.Unlock()
+
+	if db.closed {
+		if writable {
+			db.mu.Unlock()
+		}
+		return nil, ErrDatabaseClosed
+	}
+
+	db.stats.txN.Add(1)
+
+	meta := *db.Meta
+	tx := &Tx{
 		db:         db,
 		writable:   writable,
+		meta:       &meta,
 		dirtyNodes: make(map[int]*Node),
 		allocated:  []int{},
 		root:       db.Root,
-	}, nil
+	}
+
+	// Readers keep the pager's mapping in place for as long as they may hold slices of it
+	if !writable {
+		db.Pager.mmapLock.RLock()
+		db.readers[tx] = struct{}{}
+	}
+
+	return tx, nil
+}
+
+// releaseFreedPages hands pages freed by earlier commits back to the pager once no open
+// read transaction is pinned to a snapshot older than the commit that freed them.
+func (db *DB) releaseFreedPages() {
+	db.metaMu.RLock()
+	minTxID := db.Meta.TxID
+	for tx := range db.readers {
+		if tx.meta.TxID < minTxID {
+			minTxID = tx.meta.TxID
+		}
+	}
+	db.metaMu.RUnlock()
+
+	db.Pager.releasePending(minTxID)
 }
 
-// Open opens or creates a database file and initializes a DB instance.
+// removeReader unregisters a finished read transaction.
+func (db *DB) removeReader(tx *Tx) {
+	db.metaMu.Lock()
+	delete(db.readers, tx)
+	db.metaMu.Unlock()
+
+	db.Pager.mmapLock.RUnlock()
+}
+
+// Open opens or creates a database file with DefaultOptions and initializes a DB instance.
 func Open(filename string) (*DB, error) {
-	pager, err := NewPager(filename)
+	return OpenWithOptions(filename, nil)
+}
+
+// OpenWithOptions opens or creates a database file configured by options and initializes a DB instance.
+// A nil options uses DefaultOptions.
+func OpenWithOptions(filename string, options *Options) (*DB, error) {
+	opts, err := resolveOptions(options)
 	if err != nil {
 		return nil, err
 	}
 
+	pager, err := openPager(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return openPagerDB(pager, opts)
+}
+
+// OpenStorage opens the database kept in storage, or initializes a new one if the storage is empty,
+// configured by options. A nil options uses DefaultOptions, and FileMode has no effect.
+// The database takes over the storage and closes it on Close, or right away if opening fails.
+// Only a FileStorage is locked against other processes and can be opened with Options.Mmap.
+func OpenStorage(storage Storage, options *Options) (*DB, error) {
+	opts, err := resolveOptions(options)
+	if err != nil {
+		storage.Close()
+		return nil, err
+	}
+
+	pager, err := newPager(storage, opts)
+	if err != nil {
+		storage.Close()
+		return nil, err
+	}
+
+	return openPagerDB(pager, opts)
+}
+
+// resolveOptions fills in the defaults of options, which may be nil, and validates them.
+func resolveOptions(options *Options) (*Options, error) {
+	if options == nil {
+		options = DefaultOptions
+	}
+	opts := *options
+	if opts.FileMode == 0 {
+		opts.FileMode = DefaultOptions.FileMode
+	}
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+// openPagerDB opens the database in the pager's storage, closing the pager if that fails.
+func openPagerDB(pager *Pager, options *Options) (*DB, error) {
+	db, err := openDB(pager, options)
+	if err != nil {
+		pager.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// openDB initializes a new database in the pager's storage, or loads the committed state of an existing one.
+func openDB(pager *Pager, options *Options) (*DB, error) {
+
 	// Check if file is new (size 0)
-	info, err := pager.file.Stat()
+	size, err := pager.storage.Size()
 	if err != nil {
 		return nil, err
 	}
 
-	if info.Size() == 0 {
+	if size == 0 {
+		if options.ReadOnly {
+			return nil, fmt.Errorf("cannot create a new database in read-only mode")
+		}
+
 		//New Database
 		meta := &Meta{
-			Magic:    DBMagic,
-			Root:     1,
-			FreeList: 0,
+			Magic:     DBMagic,
+			Version:   MetaVersion,
+			Root:      2,
+			FreeList:  0,
+			PageCount: 3,
+			PageSize:  uint32(pager.pageSize),
 		}
 
-		metaBytes := make([]byte, PageSize)
-		meta.serialize(metaBytes)
-
 		rootNode := &Node{
-			data: make([]byte, PageSize),
+			data: make([]byte, pager.pageSize),
 		}
 		rootNode.data[0] = byte(NodeLeaf)
 		binary.LittleEndian.PutUint16(rootNode.data[1:3], 0) //key count 0
+		rootNode.setChecksum()
 
-		err = pager.Write(MetaPageID, metaBytes)
+		err = pager.Write(int(meta.Root), rootNode.data)
 		if err != nil {
-			return nil, fmt.Errorf("failed to write meta page: %w", err)
+			return nil, fmt.Errorf("failed to write root node page: %w", err)
+		}
+
+		// Initialize both meta pages; the one with TxID 1 is the current one
+		for txID := uint64(0); txID < 2; txID++ {
+			meta.TxID = txID
+			metaBytes := make([]byte, pager.pageSize)
+			meta.serialize(metaBytes)
+
+			err = pager.Write(meta.pageID(), metaBytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to write meta page: %w", err)
+			}
 		}
 
-		err = pager.Write(1, rootNode.data)
+		err = pager.Sync()
 		if err != nil {
-			return nil, fmt.Errorf("failed to write root node page: %w", err)
+			return nil, fmt.Errorf("failed to sync new database: %w", err)
 		}
 
-		// Return DB instance where Root is 1 and meta is the new struct
-		return &DB{
-			Pager: pager,
-			Root:  1,
-			Meta:  meta,
-		}, nil
+		// Return DB instance where Root is the empty leaf and meta is the new struct
+		return newDB(pager, meta, options)
 	}
 
 	// filesize >0  existing db
-	metabytes, err := pager.Read(MetaPageID)
+	meta, err := readMeta(pager)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read meta page")
+		return nil, err
 	}
 
-	meta := &Meta{}
-	meta.deserialize(metabytes)
+	// Options.PageSize only applies to new databases; an existing one keeps its own
+	pager.pageSize = int(meta.PageSize)
 
-	if err := meta.validate(); err != nil {
-		return nil, err
+	// Anything past the high-water mark was written by a transaction that never committed
+	pager.numPages = int(meta.PageCount)
+
+	if err := pager.readFreelist(int(meta.FreeList)); err != nil {
+		return nil, fmt.Errorf("failed to load freelist: %w", err)
 	}
 	// Return a DB instance where Root is set to meta.Root
+	return newDB(pager, meta, options)
+}
+
+// newDB returns a DB for the committed meta, preallocating and mapping the file as the options ask.
+func newDB(pager *Pager, meta *Meta, options *Options) (*DB, error) {
+	if options.InitialMmapSize > 0 {
+		if err := pager.grow(options.InitialMmapSize); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Mmap {
+		if err := pager.EnableMmap(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &DB{
-		Pager: pager,
-		Root:  int(meta.Root),
-		Meta:  meta,
+		Pager:    pager,
+		Root:     int(meta.Root),
+		Meta:     meta,
+		readers:  make(map[*Tx]struct{}),
+		readOnly: options.ReadOnly,
+		noSync:   options.NoSync,
+
+		MaxBatchSize:  DefaultMaxBatchSize,
+		MaxBatchDelay: DefaultMaxBatchDelay,
 	}, nil
 }
 
-// It automatically commits if the function returns nil, or rolls back if it returns an error.
-func (db *DB) Update(fn func(tx *Tx) error) error {
+// Close closes the database file. It waits for open transactions to finish, and no new ones can be started
+// afterwards, so it must not be called while the calling goroutine has a transaction open.
+func (db *DB) Close() error {
+	// Wait for the writer; read transactions are waited for by the pager
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	db.metaMu.Lock()
+	if db.closed {
+		db.metaMu.Unlock()
+		return ErrDatabaseClosed
+	}
+	db.closed = true
+	db.metaMu.Unlock()
+
+	return db.Pager.Close()
+}
+
+// readMeta reads both meta pages and returns the valid one with the highest transaction ID,
+// so a crash in the middle of a meta write falls back to the previous commit.
+// The second meta page starts at the page size stored in the meta, so when the first meta page
+// is unreadable it is looked for at every supported page size.
+func readMeta(pager *Pager) (*Meta, error) {
+	best, firstErr := pager.readMetaAt(MetaPageID0, 0)
+
+	sizes := []int{}
+	if best != nil {
+		sizes = append(sizes, int(best.PageSize))
+	} else {
+		for size := MinPageSize; size <= MaxPageSize; size *= 2 {
+			sizes = append(sizes, size)
+		}
+	}
+
+	for _, size := range sizes {
+		meta, err := pager.readMetaAt(MetaPageID1, size)
+		if err == nil && int(meta.PageSize) != size {
+			err = fmt.Errorf("invalid database file: meta page %d has page size %d", MetaPageID1, meta.PageSize)
+		}
+
+		if err == nil {
+			if best == nil || meta.TxID > best.TxID {
+				best = meta
+			}
+			break
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if best == nil {
+		return nil, firstErr
+	}
+	return best, nil
+}
+
+// readMetaAt reads and validates the meta stored in the given meta page, assuming the given page size.
+func (p *Pager) readMetaAt(pageID int, pageSize int) (*Meta, error) {
+	buf := make([]byte, metaSize)
+	_, err := p.storage.ReadAt(buf, int64(pageID*pageSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read meta page %d: %w", pageID, err)
+	}
+
+	meta := &Meta{}
+	meta.deserialize(buf)
+	if err := meta.validate(); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// Update executes a function within a managed read-write transaction.
+// It automatically commits if the function returns nil, or rolls back if it returns an error.
+// If the function panics, the transaction is rolled back and the panic carries on to the caller.
+func (db *DB) Update(fn func(tx *Tx) error) error {
 	tx, err := db.Begin(true)
 	if err != nil {
 		return err
 	}
 
-	// Defer a rollback. If Commit() is called successfully later,
-	// this rollback will be a no-op (or safe cleanup).
-	// If the user function panics or returns error, this ensures safety.
+	// Runs when fn returns an error, panics or exits the goroutine, and does nothing after a commit
 	defer tx.Rollback()
 
 	if err := fn(tx); err != nil {
@@ -116,32 +366,37 @@ func (db *DB) Update(fn func(tx *Tx) error) error {
 }
 
 // View executes a function within a managed read-only transaction.
+// It reads the snapshot of the last commit and runs concurrently with writers.
+// If the function panics, the transaction is rolled back and the panic carries on to the caller.
 func (db *DB) View(fn func(tx *Tx) error) error {
-	db.mu.RLock()
-	defer db.mu.RUnlock()
-
 	tx, err := db.Begin(false)
 	if err != nil {
 		return err
 	}
+
+	// Releases the snapshot however fn finishes, also when it panics
 	defer tx.Rollback()
 
 	return fn(tx)
 }
 
-func (db *DB) writeMeta() error {
-	buf := make([]byte, PageSize)
+// writeMeta writes the meta to the page its TxID selects and syncs it,
+// leaving the other meta page with the previous commit untouched.
+func (db *DB) writeMeta(meta *Meta) error {
+	buf := make([]byte, db.Pager.pageSize)
 
-	db.Meta.serialize(buf)
+	meta.serialize(buf)
 
-	err := db.Pager.Write(MetaPageID, buf)
+	err := db.Pager.Write(meta.pageID(), buf)
 	if err != nil {
 		return fmt.Errorf("failed to write meta page: %w", err)
 	}
 
-	err = db.Pager.Sync()
-	if err != nil {
-		return fmt.Errorf("failed to sync meta page: %w", err)
+	if !db.noSync {
+		err = db.Pager.Sync()
+		if err != nil {
+			return fmt.Errorf("failed to sync meta page: %w", err)
+		}
 	}
 
 	return nil
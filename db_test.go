@@ -0,0 +1,306 @@
+package gokv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openTestDB opens a database at path with options, failing the test if it can't.
+func openTestDB(t *testing.T, path string, options *Options) *DB {
+	t.Helper()
+
+	db, err := OpenWithOptions(path, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// tempDBPath returns the path of a database file in a fresh temporary directory.
+func tempDBPath(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "test.db")
+}
+
+// markReachable adds every page reachable from the tree rooted at root to set, following branches,
+// nested buckets and overflow chains.
+func markReachable(t *testing.T, tx *Tx, root int, set map[int]bool) {
+	t.Helper()
+
+	set[root] = true
+	data, err := tx.db.Pager.Read(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &Node{data: data}
+	for i := uint16(0); i < node.getKeyCount(); i++ {
+		switch {
+		case node.getType() == NodeBranch:
+			markReachable(t, tx, node.getChild(i), set)
+		case node.getFlags(i)&EntryFlagBucket != 0:
+			_, v := node.getLeafKeyValue(i)
+			markReachable(t, tx, int(binary.LittleEndian.Uint64(v)), set)
+		case node.getFlags(i)&EntryFlagOverflow != 0:
+			_, ref := node.getLeafKeyValue(i)
+			id, _, err := decodeOverflowRef(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for id != 0 {
+				set[id] = true
+				data, err := tx.db.Pager.Read(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				id = (&Node{data: data}).getOverflowNext()
+			}
+		}
+	}
+}
+
+// checkPageAccounting fails the test unless every page of the file is exactly one of: a meta page,
+// reachable from the root, part of the persisted freelist, or free.
+func checkPageAccounting(t *testing.T, db *DB) {
+	t.Helper()
+
+	db.View(func(tx *Tx) error {
+		set := map[int]bool{0: true, 1: true}
+		markReachable(t, tx, tx.db.Root, set)
+
+		for _, id := range db.Pager.freelistPages {
+			if set[id] {
+				t.Fatalf("freelist page %d is also in use", id)
+			}
+			set[id] = true
+		}
+
+		free := append([]int{}, db.Pager.freePages...)
+		for _, ids := range db.Pager.pending {
+			free = append(free, ids...)
+		}
+		for _, id := range free {
+			if set[id] {
+				t.Fatalf("free page %d is also in use", id)
+			}
+			set[id] = true
+		}
+
+		if len(set) != db.Pager.numPages {
+			var missing []int
+			for id := 0; id < db.Pager.numPages; id++ {
+				if !set[id] {
+					missing = append(missing, id)
+				}
+			}
+			t.Fatalf("%d of %d pages accounted for, leaked pages %v", len(set), db.Pager.numPages, missing)
+		}
+		return nil
+	})
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	// Half of the writers manage their own transactions, the other half use Update
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := []byte(fmt.Sprintf("g%d-%d", g, i))
+				if g%2 == 1 {
+					if err := db.Update(func(tx *Tx) error { return tx.Put(key, []byte("v")) }); err != nil {
+						t.Error(err)
+						return
+					}
+					continue
+				}
+
+				tx, err := db.Begin(true)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if err := tx.Put(key, []byte("v")); err != nil {
+					tx.Rollback()
+					t.Error(err)
+					return
+				}
+				if err := tx.Commit(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	db.View(func(tx *Tx) error {
+		n := 0
+		c := tx.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			n++
+		}
+		if n != 400 {
+			t.Errorf("%d keys after concurrent writes, want 400", n)
+		}
+		return nil
+	})
+}
+
+func TestClosedTx(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("a"), []byte("v")) }); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := tx.Cursor()
+	c.First()
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.Get([]byte("a")); err != ErrTxClosed {
+		t.Errorf("Get = %v, want ErrTxClosed", err)
+	}
+	if _, err := tx.Bucket([]byte("b")); err != ErrTxClosed {
+		t.Errorf("Bucket = %v, want ErrTxClosed", err)
+	}
+	if _, err := tx.Stats(); err != ErrTxClosed {
+		t.Errorf("Stats = %v, want ErrTxClosed", err)
+	}
+	if k, _ := c.Next(); k != nil || c.Err() != ErrTxClosed {
+		t.Errorf("cursor Next = %q with %v, want ErrTxClosed", k, c.Err())
+	}
+	if err := tx.Rollback(); err != ErrTxClosed {
+		t.Errorf("second Rollback = %v, want ErrTxClosed", err)
+	}
+	if err := tx.Commit(); err != ErrTxClosed {
+		t.Errorf("Commit = %v, want ErrTxClosed", err)
+	}
+
+	// A committed write transaction is closed as well, and its lock is released
+	wtx, err := db.Begin(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wtx.Put([]byte("b"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if err := wtx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wtx.Rollback(); err != ErrTxClosed {
+		t.Errorf("Rollback after Commit = %v, want ErrTxClosed", err)
+	}
+	if err := db.Update(func(tx *Tx) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateRollsBackOnPanic(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the panic from the function", r)
+			}
+		}()
+		db.Update(func(tx *Tx) error {
+			if err := tx.Put([]byte("p"), []byte("v")); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+
+	// The writer lock was released, and the write is gone
+	err := db.Update(func(tx *Tx) error {
+		if _, err := tx.Get([]byte("p")); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Get of a write from a panicked Update = %v, want ErrKeyNotFound", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateRollsBackOnGoexit(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	// runtime.Goexit is what t.Fatal calls inside an Update function
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		db.Update(func(tx *Tx) error {
+			tx.Put([]byte("p"), []byte("v"))
+			runtime.Goexit()
+			return nil
+		})
+	}()
+	<-done
+
+	err := db.Update(func(tx *Tx) error {
+		if _, err := tx.Get([]byte("p")); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Get of a write from an exited Update = %v, want ErrKeyNotFound", err)
+		}
+		return tx.Put([]byte("q"), []byte("v"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloseWaitsForReaders(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("a"), []byte("v")) }); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan error)
+	go func() { closed <- db.Close() }()
+
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a read transaction was open")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if _, err := tx.Get([]byte("a")); err != nil {
+		t.Errorf("Get while Close waits = %v", err)
+	}
+	tx.Rollback()
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Begin(false); err != ErrDatabaseClosed {
+		t.Errorf("Begin after Close = %v, want ErrDatabaseClosed", err)
+	}
+	if err := db.Update(func(*Tx) error { return nil }); err != ErrDatabaseClosed {
+		t.Errorf("Update after Close = %v, want ErrDatabaseClosed", err)
+	}
+}
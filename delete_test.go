@@ -0,0 +1,142 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDelete(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+	defer func() { db.Close() }()
+
+	rng := rand.New(rand.NewSource(1))
+	want := map[string]string{}
+	for round := 0; round < 40; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 200; i++ {
+				k := fmt.Sprintf("key-%05d", rng.Intn(3000))
+				if _, ok := want[k]; ok {
+					if err := tx.Delete([]byte(k)); err != nil {
+						return fmt.Errorf("delete %s: %w", k, err)
+					}
+					delete(want, k)
+					continue
+				}
+
+				v := fmt.Sprintf("val-%d-%s", rng.Intn(1000), k)
+				if rng.Intn(5) == 0 {
+					v += strings.Repeat("x", rng.Intn(300))
+				}
+				if err := tx.Put([]byte(k), []byte(v)); err != nil {
+					return fmt.Errorf("put %s: %w", k, err)
+				}
+				want[k] = v
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if round%10 == 9 {
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+			db = openTestDB(t, path, nil)
+		}
+
+		db.View(func(tx *Tx) error {
+			for k, v := range want {
+				got, err := tx.Get([]byte(k))
+				if err != nil || string(got) != v {
+					t.Fatalf("round %d: Get(%s) = %q, %v, want %q", round, k, got, err, v)
+				}
+			}
+			return nil
+		})
+	}
+
+	err := db.Update(func(tx *Tx) error {
+		for k := range want {
+			if err := tx.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.View(func(tx *Tx) error {
+		root, err := tx.getNode(tx.root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root.getType() != NodeLeaf || root.getKeyCount() != 0 {
+			t.Fatalf("root after deleting every key: type %d with %d keys, want an empty leaf", root.getType(), root.getKeyCount())
+		}
+		return nil
+	})
+}
+
+func TestDeleteMissingKey(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	err := db.Update(func(tx *Tx) error {
+		if err := tx.Put([]byte("a"), []byte("1")); err != nil {
+			return err
+		}
+		return tx.Delete([]byte("b"))
+	})
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Delete of a missing key = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestDeleteAndRewriteKeepsPagesAccountedFor(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	err := db.Update(func(tx *Tx) error {
+		for i := 0; i < 500; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%04d", i)), []byte("v0")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPageAccounting(t, db)
+
+	// Alternate between deleting every third key and writing it back, so that nodes keep merging
+	// and splitting.
+	for round := 1; round < 30; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 500; i += 3 {
+				k := []byte(fmt.Sprintf("k%04d", i))
+				if round%2 == 1 {
+					if err := tx.Delete(k); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := tx.Put(k, []byte(fmt.Sprintf("v%d", round))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("round %d: %v", round, err)
+		}
+		checkPageAccounting(t, db)
+	}
+}
@@ -0,0 +1,82 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by Open, Begin and Close.
+var (
+	// ErrDatabaseLocked is returned by Open when another process holds a conflicting lock on the file
+	// and it is not released within Options.Timeout.
+	ErrDatabaseLocked = errors.New("database is locked by another process")
+
+	// ErrDatabaseClosed is returned when a transaction is started on a database that has been closed.
+	ErrDatabaseClosed = errors.New("database is closed")
+
+	// ErrDatabaseReadOnly is returned when a write transaction is started on a database opened read-only.
+	ErrDatabaseReadOnly = errors.New("database is read-only")
+
+	// ErrUnsupportedFormat is returned by Open for a GoKV file whose format version this build can't read,
+	// such as one written before the format was versioned.
+	ErrUnsupportedFormat = errors.New("unsupported database format")
+)
+
+// Errors returned by transactions.
+var (
+	// ErrTxClosed is returned by every method of a transaction, and of its buckets and cursors,
+	// once it has been committed or rolled back.
+	ErrTxClosed = errors.New("transaction is closed")
+
+	// ErrTxNotWritable is returned when a read-only transaction is asked to write or commit.
+	ErrTxNotWritable = errors.New("transaction is not writable")
+)
+
+// Errors returned by reads and writes of keys and buckets.
+var (
+	// ErrKeyNotFound is returned when a key that must exist does not.
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrKeyExists is returned by Insert when the key already exists.
+	ErrKeyExists = errors.New("key already exists")
+
+	// ErrKeyRequired is returned when writing an empty key.
+	ErrKeyRequired = errors.New("key required")
+
+	// ErrKeyTooLarge is returned when a key or bucket name is longer than MaxKeySize for the page size.
+	ErrKeyTooLarge = errors.New("key too large")
+
+	// ErrValueTooLarge is returned when a value is longer than MaxValueSize.
+	ErrValueTooLarge = errors.New("value too large")
+
+	// ErrIncompatibleValue is returned when a key is used as a value but holds a bucket, or the other way around.
+	ErrIncompatibleValue = errors.New("incompatible value")
+
+	// ErrBucketNotFound is returned when a bucket does not exist.
+	ErrBucketNotFound = errors.New("bucket not found")
+
+	// ErrBucketExists is returned by CreateBucket when the bucket already exists.
+	ErrBucketExists = errors.New("bucket already exists")
+
+	// ErrBucketNameRequired is returned when creating a bucket with an empty name.
+	ErrBucketNameRequired = errors.New("bucket name required")
+
+	// ErrNodeFull is returned when an entry does not fit in a node. Writes handle it by splitting the node.
+	ErrNodeFull = errors.New("node is full")
+)
+
+// CorruptionError reports a page whose contents on disk are not what GoKV wrote.
+// Offset is the byte offset within the page where the problem was found, or -1 when the problem is
+// not tied to particular bytes of the page.
+type CorruptionError struct {
+	PageID int
+	Offset int
+	Reason string
+}
+
+func (e *CorruptionError) Error() string {
+	if e.Offset >= 0 {
+		return fmt.Sprintf("corruption detected in page %d at offset %d: %s", e.PageID, e.Offset, e.Reason)
+	}
+	return fmt.Sprintf("corruption detected in page %d: %s", e.PageID, e.Reason)
+}
@@ -0,0 +1,133 @@
+package gokv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	err := db.Update(func(tx *Tx) error {
+		if err := tx.Put([]byte("a"), []byte("1")); err != nil {
+			return err
+		}
+
+		if _, err := tx.Get([]byte("missing")); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Get of a missing key = %v, want ErrKeyNotFound", err)
+		}
+		if err := tx.Insert([]byte("a"), []byte("x")); !errors.Is(err, ErrKeyExists) {
+			t.Errorf("Insert of an existing key = %v, want ErrKeyExists", err)
+		}
+		if err := tx.Replace([]byte("missing"), []byte("x")); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Replace of a missing key = %v, want ErrKeyNotFound", err)
+		}
+		if err := tx.Put(nil, []byte("x")); !errors.Is(err, ErrKeyRequired) {
+			t.Errorf("Put with an empty key = %v, want ErrKeyRequired", err)
+		}
+		if err := tx.Put(bytes.Repeat([]byte("k"), 5000), []byte("x")); !errors.Is(err, ErrKeyTooLarge) {
+			t.Errorf("Put with a 5000 byte key = %v, want ErrKeyTooLarge", err)
+		}
+		if _, err := tx.CreateBucket(nil); !errors.Is(err, ErrBucketNameRequired) {
+			t.Errorf("CreateBucket with an empty name = %v, want ErrBucketNameRequired", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.View(func(tx *Tx) error {
+		if err := tx.Put([]byte("a"), []byte("b")); !errors.Is(err, ErrTxNotWritable) {
+			t.Errorf("Put in a read transaction = %v, want ErrTxNotWritable", err)
+		}
+		if err := tx.Commit(); !errors.Is(err, ErrTxNotWritable) {
+			t.Errorf("Commit of a read transaction = %v, want ErrTxNotWritable", err)
+		}
+		return nil
+	})
+}
+
+func TestCorruptOffsetTable(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	err := db.Update(func(tx *Tx) error {
+		for i := 0; i < 500; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Point the fourth entry of a leaf past the end of the page, with a valid checksum
+	var leafID int
+	db.View(func(tx *Tx) error {
+		root, err := tx.getNode(tx.root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leafID = root.getChild(1)
+		return nil
+	})
+	data, err := db.Pager.Read(leafID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &Node{data: append([]byte(nil), data...)}
+	badOffset := NodeHeaderSize + 3*OffsetSize
+	binary.LittleEndian.PutUint16(node.data[badOffset:], 65000)
+	node.setChecksum()
+	if err := db.Pager.Write(leafID, node.data); err != nil {
+		t.Fatal(err)
+	}
+
+	db.View(func(tx *Tx) error {
+		c := tx.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		}
+		var ce *CorruptionError
+		if !errors.As(c.Err(), &ce) || ce.PageID != leafID || ce.Offset != badOffset {
+			t.Errorf("cursor error %v, want a CorruptionError for page %d at offset %d", c.Err(), leafID, badOffset)
+		}
+		return nil
+	})
+
+	// Writes into the corrupted leaf fail instead of panicking
+	err = db.Update(func(tx *Tx) error {
+		for i := 0; i < 500; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("w")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var ce *CorruptionError
+	if !errors.As(err, &ce) || ce.PageID != leafID {
+		t.Fatalf("Put into a corrupted leaf = %v, want a CorruptionError for page %d", err, leafID)
+	}
+}
+
+func TestCorruptionErrorOffset(t *testing.T) {
+	tests := []struct {
+		err  *CorruptionError
+		want string
+	}{
+		{&CorruptionError{PageID: 7, Offset: 0, Reason: "bad type"}, "corruption detected in page 7 at offset 0: bad type"},
+		{&CorruptionError{PageID: 7, Offset: 12, Reason: "bad entry"}, "corruption detected in page 7 at offset 12: bad entry"},
+		{&CorruptionError{PageID: 7, Offset: -1, Reason: "leaked"}, "corruption detected in page 7: leaked"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !unix && !windows
+
+package gokv
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// flock has no file locking to use on this platform. Opening the file unlocked would let two
+// processes write it at once, so Open fails instead.
+func flock(file *os.File, exclusive bool, timeout time.Duration) error {
+	return fmt.Errorf("cannot lock the database file: file locking is not supported on %s", runtime.GOOS)
+}
+
+// funlock has nothing to release without a lock.
+func funlock(file *os.File) error {
+	return nil
+}
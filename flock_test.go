@@ -0,0 +1,61 @@
+//go:build unix || windows
+
+package gokv
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFlockExclusive(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+
+	if _, err := Open(path); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("second Open = %v, want ErrDatabaseLocked", err)
+	}
+	if _, err := OpenWithOptions(path, &Options{ReadOnly: true}); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("read-only Open of a writable database = %v, want ErrDatabaseLocked", err)
+	}
+
+	start := time.Now()
+	if _, err := OpenWithOptions(path, &Options{Timeout: 200 * time.Millisecond}); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("Open with a timeout = %v, want ErrDatabaseLocked", err)
+	}
+	if waited := time.Since(start); waited < 200*time.Millisecond {
+		t.Fatalf("gave up on the lock after %v, before the timeout", waited)
+	}
+
+	// The lock is taken as soon as the holder lets go
+	closed := make(chan error)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		closed <- db.Close()
+	}()
+	db2, err := OpenWithOptions(path, &Options{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	db2.Close()
+}
+
+func TestFlockShared(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r1 := openTestDB(t, path, &Options{ReadOnly: true})
+	defer r1.Close()
+	r2 := openTestDB(t, path, &Options{ReadOnly: true})
+	defer r2.Close()
+
+	if _, err := Open(path); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("Open while read-only handles are open = %v, want ErrDatabaseLocked", err)
+	}
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package gokv
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// flockRetryInterval is how long flock waits between attempts while another process holds the lock.
+const flockRetryInterval = 50 * time.Millisecond
+
+// flock takes an advisory lock on the file, exclusive or shared. If another process holds a
+// conflicting lock, it retries until the timeout runs out and then returns ErrDatabaseLocked.
+func flock(file *os.File, exclusive bool, timeout time.Duration) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return ErrDatabaseLocked
+		}
+		time.Sleep(flockRetryInterval)
+	}
+}
+
+// funlock releases the lock taken by flock.
+func funlock(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
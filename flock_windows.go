@@ -0,0 +1,69 @@
+//go:build windows
+
+package gokv
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	modkernel32      = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	// LockFileEx flags
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+
+	// errorLockViolation is returned by LockFileEx when another handle holds a conflicting lock
+	errorLockViolation syscall.Errno = 33
+
+	// flockRetryInterval is how long flock waits between attempts while another process holds the lock.
+	flockRetryInterval = 50 * time.Millisecond
+)
+
+// lockRange returns the byte range flock locks. Windows byte-range locks are mandatory, so it is
+// the last byte of the largest possible file, where the lock never blocks reads and writes of pages.
+func lockRange() *syscall.Overlapped {
+	return &syscall.Overlapped{Offset: ^uint32(0), OffsetHigh: ^uint32(0)}
+}
+
+// flock takes a lock on the file with LockFileEx, exclusive or shared. If another process holds a
+// conflicting lock, it retries until the timeout runs out and then returns ErrDatabaseLocked.
+func flock(file *os.File, exclusive bool, timeout time.Duration) error {
+	flags := uint32(lockfileFailImmediately)
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		r, _, err := procLockFileEx.Call(file.Fd(), uintptr(flags), 0, 1, 0, uintptr(unsafe.Pointer(lockRange())))
+		if r != 0 {
+			return nil
+		}
+		if !errors.Is(err, errorLockViolation) {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return ErrDatabaseLocked
+		}
+		time.Sleep(flockRetryInterval)
+	}
+}
+
+// funlock releases the lock taken by flock.
+func funlock(file *os.File) error {
+	r, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(lockRange())))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package gokv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+const (
+	// A freelist page stores the number of page IDs it holds in the key count field,
+	// followed by the page ID of the next freelist page (0 ends the chain) and the IDs themselves.
+	FreelistNextSize   = 4
+	FreelistHeaderSize = NodeHeaderSize + FreelistNextSize
+	FreelistEntrySize  = 4
+)
+
+// freelistPageCapacity returns the number of free page IDs that fit in a single freelist page.
+func freelistPageCapacity(pageSize int) int {
+	return (pageSize - FreelistHeaderSize) / FreelistEntrySize
+}
+
+// writeFreelist serializes the free list as it will look once this transaction commits
+// into freshly allocated freelist pages, and returns their page IDs.
+// Pages freed by this transaction, the pages holding the previous free list and pages
+// held back for open readers are written as free, but the pager only hands them out
+// again after the new meta page is on disk and no reader can reach them.
+func (tx *Tx) writeFreelist() []int {
+	p := tx.db.Pager
+	capacity := freelistPageCapacity(p.pageSize)
+
+	pending := make([]int, 0, len(tx.freed)+len(p.freelistPages))
+	pending = append(pending, tx.freed...)
+	pending = append(pending, p.freelistPages...)
+
+	// Pages still held back for open readers are free as far as a restarted database is concerned
+	for _, pageIDs := range p.pending {
+		pending = append(pending, pageIDs...)
+	}
+
+	// Allocating a list page may take it off the free list, so recount after each allocation
+	var listPages []int
+	for len(listPages)*capacity < len(p.freePages)+len(pending) {
+		listPages = append(listPages, tx.allocateNode())
+	}
+
+	ids := make([]int, 0, len(p.freePages)+len(pending))
+	ids = append(ids, p.freePages...)
+	ids = append(ids, pending...)
+	sort.Ints(ids)
+
+	for i, pageID := range listPages {
+		start := min(i*capacity, len(ids))
+		end := min(start+capacity, len(ids))
+
+		next := 0
+		if i+1 < len(listPages) {
+			next = listPages[i+1]
+		}
+
+		node := &Node{data: make([]byte, p.pageSize)}
+		node.data[0] = byte(NodeFreelist)
+		binary.LittleEndian.PutUint16(node.data[1:3], uint16(end-start))
+		binary.LittleEndian.PutUint32(node.data[NodeHeaderSize:FreelistHeaderSize], uint32(next))
+
+		for j, id := range ids[start:end] {
+			pos := FreelistHeaderSize + j*FreelistEntrySize
+			binary.LittleEndian.PutUint32(node.data[pos:pos+FreelistEntrySize], uint32(id))
+		}
+
+		tx.dirtyNodes[pageID] = node
+	}
+
+	return listPages
+}
+
+// readFreelist loads the free list stored in the chain of freelist pages starting at pageID.
+func (p *Pager) readFreelist(pageID int) error {
+	freePages, listPages, err := p.loadFreelist(pageID, p.numPages)
+	if err != nil {
+		return err
+	}
+
+	p.freePages = freePages
+	p.freelistPages = listPages
+	return nil
+}
+
+// loadFreelist reads the chain of freelist pages starting at pageID in a database of pageCount pages.
+// Returns the free page IDs and the pages holding the list.
+func (p *Pager) loadFreelist(pageID int, pageCount int) ([]int, []int, error) {
+	var freePages, listPages []int
+
+	for pageID != 0 {
+		if pageID >= pageCount {
+			return nil, nil, &CorruptionError{PageID: pageID, Offset: -1, Reason: fmt.Sprintf("freelist page is beyond the end of the database of %d pages", pageCount)}
+		}
+		if len(listPages) >= pageCount {
+			return nil, nil, fmt.Errorf("freelist chain starting at page %d loops", listPages[0])
+		}
+
+		data, err := p.Read(pageID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read freelist page %d: %w", pageID, err)
+		}
+
+		if !(&Node{data: data}).verifyChecksum() {
+			return nil, nil, &CorruptionError{PageID: pageID, Offset: ChecksumOffset, Reason: "checksum mismatch"}
+		}
+
+		if data[0] != NodeFreelist {
+			return nil, nil, &CorruptionError{PageID: pageID, Offset: 0, Reason: fmt.Sprintf("page has node type %d, expected a freelist page", data[0])}
+		}
+
+		count := int(binary.LittleEndian.Uint16(data[1:3]))
+		if count > freelistPageCapacity(p.pageSize) {
+			return nil, nil, &CorruptionError{PageID: pageID, Offset: 1, Reason: fmt.Sprintf("freelist page has invalid count %d", count)}
+		}
+
+		for j := 0; j < count; j++ {
+			pos := FreelistHeaderSize + j*FreelistEntrySize
+			freePages = append(freePages, int(binary.LittleEndian.Uint32(data[pos:pos+FreelistEntrySize])))
+		}
+
+		listPages = append(listPages, pageID)
+		pageID = int(binary.LittleEndian.Uint32(data[NodeHeaderSize:FreelistHeaderSize]))
+	}
+
+	return freePages, listPages, nil
+}
@@ -0,0 +1,47 @@
+package gokv
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFreelistPersisted(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+	defer func() { db.Close() }()
+
+	big := make([]byte, 50000)
+	var pageCounts []int
+	for round := 0; round < 20; round++ {
+		if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("big"), big) }); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Update(func(tx *Tx) error { return tx.Delete([]byte("big")) }); err != nil {
+			t.Fatal(err)
+		}
+		checkPageAccounting(t, db)
+
+		want := append([]int{}, db.Pager.freePages...)
+		for _, ids := range db.Pager.pending {
+			want = append(want, ids...)
+		}
+		slices.Sort(want)
+
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+		db = openTestDB(t, path, nil)
+
+		got := slices.Sorted(slices.Values(db.Pager.freePages))
+		if !slices.Equal(got, want) {
+			t.Fatalf("round %d: reopened freelist %v, want %v", round, got, want)
+		}
+		checkPageAccounting(t, db)
+		pageCounts = append(pageCounts, db.Pager.numPages)
+	}
+
+	// The freed overflow pages are reused, so the file stops growing after the first rounds.
+	if first, last := pageCounts[2], pageCounts[len(pageCounts)-1]; last != first {
+		t.Fatalf("file grew from %d to %d pages, page counts %v", first, last, pageCounts)
+	}
+}
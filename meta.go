@@ -3,34 +3,107 @@ package gokv
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
+	"slices"
 )
 
 const (
-	MetaPageID = 0
-	DBMagic    = 0xDEADBEEF // A signature to verify this is GOKV's db file
+	// The two meta pages are written alternately, so a torn meta write
+	// always leaves the previously committed one intact
+	MetaPageID0 = 0
+	MetaPageID1 = 1
+	DBMagic     = 0xDEADBEEF // A signature to verify this is GOKV's db file
+
+	// MetaVersion is the version of the file format this build reads and writes. It changes whenever the
+	// layout of the file changes. Files written before the version field existed read as version 0.
+	MetaVersion = 3
+
+	// The checksum covers everything stored before it, including the reserved bytes after the last field.
+	// It stays at this offset in every format version, so a meta page can be verified before its version is trusted.
+	metaChecksumOffset = 60
+	metaSize           = metaChecksumOffset + 4
 )
 
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
 type Meta struct {
-	Magic    uint32
-	Root     uint32
-	FreeList uint32
+	Magic     uint32
+	Root      uint32
+	FreeList  uint32 // first page of the freelist chain, 0 if there are no free pages
+	PageCount uint32 // high-water mark: pages at or beyond it are not part of the database
+	TxID      uint64 // incremented by every commit; the valid meta page with the highest one wins
+	Version   uint32 // file format version, MetaVersion for files this build writes
+	PageSize  uint32 // chosen when the database is created; meta page 1 starts at this offset
+	Checksum  uint32
+
+	// The bytes the checksum covers as deserialize read them, since a meta page written in another
+	// format version may store fields this build doesn't know about
+	stored []byte
 }
 
 func (m *Meta) serialize(buf []byte) {
-	binary.LittleEndian.PutUint32(buf[0:4], m.Magic)
-	binary.LittleEndian.PutUint32(buf[4:8], m.Root)
-	binary.LittleEndian.PutUint32(buf[8:12], m.FreeList)
+	clear(buf[:metaChecksumOffset])
+	m.putFields(buf)
+	m.Checksum = crc32.Checksum(buf[:metaChecksumOffset], crcTable)
+	binary.LittleEndian.PutUint32(buf[metaChecksumOffset:metaSize], m.Checksum)
 }
 
 func (m *Meta) deserialize(buf []byte) {
 	m.Magic = binary.LittleEndian.Uint32(buf[0:4])
 	m.Root = binary.LittleEndian.Uint32(buf[4:8])
 	m.FreeList = binary.LittleEndian.Uint32(buf[8:12])
+	m.PageCount = binary.LittleEndian.Uint32(buf[12:16])
+	m.TxID = binary.LittleEndian.Uint64(buf[16:24])
+	m.Version = binary.LittleEndian.Uint32(buf[24:28])
+	m.PageSize = binary.LittleEndian.Uint32(buf[28:32])
+	m.Checksum = binary.LittleEndian.Uint32(buf[metaChecksumOffset:metaSize])
+	m.stored = append([]byte(nil), buf[:metaChecksumOffset]...)
+}
+
+// putFields writes every field except the checksum.
+func (m *Meta) putFields(buf []byte) {
+	binary.LittleEndian.PutUint32(buf[0:4], m.Magic)
+	binary.LittleEndian.PutUint32(buf[4:8], m.Root)
+	binary.LittleEndian.PutUint32(buf[8:12], m.FreeList)
+	binary.LittleEndian.PutUint32(buf[12:16], m.PageCount)
+	binary.LittleEndian.PutUint64(buf[16:24], m.TxID)
+	binary.LittleEndian.PutUint32(buf[24:28], m.Version)
+	binary.LittleEndian.PutUint32(buf[28:32], m.PageSize)
+}
+
+// pageID returns the meta page this meta is written to.
+func (m *Meta) pageID() int {
+	return int(m.TxID % 2)
 }
 
 func (m *Meta) validate() error {
 	if m.Magic != DBMagic {
 		return fmt.Errorf("invalid database file: magic mismatch")
 	}
+
+	// Files from before the version field have nothing written past the page count,
+	// any other meta has to match its checksum before its version can be trusted
+	if m.Checksum == 0 && !slices.ContainsFunc(m.stored[16:], func(b byte) bool { return b != 0 }) {
+		return fmt.Errorf("%w: the file was written by an older GoKV without a format version and must be recreated", ErrUnsupportedFormat)
+	}
+	if m.Checksum != crc32.Checksum(m.stored, crcTable) {
+		return fmt.Errorf("invalid database file: meta checksum mismatch")
+	}
+
+	// The rest of the meta can only be read in the layout this build knows
+	if m.Version != MetaVersion {
+		return fmt.Errorf("%w: the file has format version %d, this build reads version %d", ErrUnsupportedFormat, m.Version, MetaVersion)
+	}
+	if !validPageSize(int(m.PageSize)) {
+		return fmt.Errorf("invalid database file: unsupported page size %d", m.PageSize)
+	}
+	if m.Root >= m.PageCount || m.FreeList >= m.PageCount {
+		return fmt.Errorf("invalid database file: root %d or freelist %d beyond page count %d", m.Root, m.FreeList, m.PageCount)
+	}
 	return nil
 }
+
+// validPageSize reports whether size is a power of two between MinPageSize and MaxPageSize.
+func validPageSize(size int) bool {
+	return size >= MinPageSize && size <= MaxPageSize && size&(size-1) == 0
+}
@@ -0,0 +1,144 @@
+package gokv
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// rewriteFile applies fn to the contents of the file at path.
+func rewriteFile(t *testing.T, path string, fn func(data []byte)) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn(data)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetaFallsBackToPreviousCommit(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("a"), []byte("1")) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("b"), []byte("2")) }); err != nil {
+		t.Fatal(err)
+	}
+	txID, current := db.Meta.TxID, db.Meta.pageID()
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tear the meta page of the last commit
+	rewriteFile(t, path, func(data []byte) {
+		copy(data[current*DefaultPageSize+5:], []byte{0xFF, 0xFF, 0xFF})
+	})
+
+	db = openTestDB(t, path, nil)
+	if db.Meta.TxID != txID-1 {
+		t.Fatalf("opened at transaction %d, want the previous commit %d", db.Meta.TxID, txID-1)
+	}
+	db.View(func(tx *Tx) error {
+		if _, err := tx.Get([]byte("a")); err != nil {
+			t.Fatalf("Get(a) from the previous commit: %v", err)
+		}
+		if _, err := tx.Get([]byte("b")); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("Get(b) from the previous commit = %v, want ErrKeyNotFound", err)
+		}
+		return nil
+	})
+
+	// The next commit overwrites the torn page
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("c"), []byte("3")) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db = openTestDB(t, path, nil)
+	defer db.Close()
+	if db.Meta.TxID != txID {
+		t.Fatalf("opened at transaction %d, want %d", db.Meta.TxID, txID)
+	}
+}
+
+func TestMetaRejectsOtherFormatVersions(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("a"), []byte("1")) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewrite both meta pages with a newer version and a matching checksum
+	rewriteFile(t, path, func(data []byte) {
+		for _, pageID := range []int{MetaPageID0, MetaPageID1} {
+			page := data[pageID*DefaultPageSize : (pageID+1)*DefaultPageSize]
+			meta := &Meta{}
+			meta.deserialize(page)
+			meta.Version = MetaVersion + 1
+			meta.serialize(page)
+		}
+	})
+
+	if _, err := Open(path); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("opening format version %d = %v, want ErrUnsupportedFormat", MetaVersion+1, err)
+	}
+}
+
+func TestMetaRejectsUnversionedFiles(t *testing.T) {
+	// A file as GoKV wrote it before the meta had a format version: the magic, root, freelist and
+	// page count on page 0 and an empty root leaf on page 1.
+	data := make([]byte, 2*DefaultPageSize)
+	binary.LittleEndian.PutUint32(data[0:4], DBMagic)
+	binary.LittleEndian.PutUint32(data[4:8], 1)
+	binary.LittleEndian.PutUint32(data[12:16], 2)
+	data[DefaultPageSize] = NodeLeaf
+
+	path := tempDBPath(t)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("opening an unversioned file = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestMetaDamageIsNotAnUnsupportedFormat(t *testing.T) {
+	// Zero the version, then the version and the checksum, of both meta pages
+	for _, ranges := range [][][2]int{{{24, 28}}, {{24, 28}, {metaChecksumOffset, metaSize}}} {
+		path := tempDBPath(t)
+		db := openTestDB(t, path, nil)
+		if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("a"), []byte("1")) }); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		rewriteFile(t, path, func(data []byte) {
+			for _, pageID := range []int{MetaPageID0, MetaPageID1} {
+				for _, r := range ranges {
+					clear(data[pageID*DefaultPageSize+r[0] : pageID*DefaultPageSize+r[1]])
+				}
+			}
+		})
+
+		_, err := Open(path)
+		if err == nil || errors.Is(err, ErrUnsupportedFormat) || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Fatalf("opening with bytes %v of both metas zeroed = %v, want a checksum mismatch", ranges, err)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package gokv
+
+import (
+	"fmt"
+	"os"
+)
+
+// mmap is not available on this platform, so the pager keeps reading pages with ReadAt.
+func mmap(file *os.File, size int) ([]byte, error) {
+	return nil, fmt.Errorf("mmap is not supported on this platform")
+}
+
+// munmap is never called without a mapping from mmap.
+func munmap(data []byte) error {
+	return nil
+}
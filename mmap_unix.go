@@ -0,0 +1,19 @@
+//go:build unix
+
+package gokv
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the first size bytes of the file into memory, read-only and shared,
+// so writes through the file show up in the mapping.
+func mmap(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmap releases a mapping created by mmap.
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,125 @@
+//go:build unix
+
+package gokv
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestMmapWithConcurrentReaders(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	if err := db.Pager.EnableMmap(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers scan the whole tree while the writer grows the file past the mapping
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errs := make(chan error, 4)
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				err := db.View(func(tx *Tx) error {
+					prev := ""
+					c := tx.Cursor()
+					for k, v := c.First(); k != nil; k, v = c.Next() {
+						if string(k) <= prev || string(v) != "val-"+string(k) {
+							return fmt.Errorf("unexpected %s = %q after %s", k, v, prev)
+						}
+						prev = string(k)
+					}
+					return c.Err()
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	for round := 0; round < 40; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 200; i++ {
+				k := fmt.Sprintf("k%03d-%04d", round, i)
+				if err := tx.Put([]byte(k), []byte("val-"+k)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	default:
+	}
+
+	// Without readers the next commit remaps the whole file
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("z"), []byte("val-z")) }); err != nil {
+		t.Fatal(err)
+	}
+	if mapped, size := len(db.Pager.mmapData), db.Pager.numPages*db.Pager.PageSize(); mapped < size {
+		t.Fatalf("mapped %d bytes of a %d byte database", mapped, size)
+	}
+
+	db.View(func(tx *Tx) error {
+		v, err := tx.Get([]byte("k039-0199"))
+		if err != nil || string(v) != "val-k039-0199" {
+			t.Fatalf("Get(k039-0199) = %q, %v", v, err)
+		}
+		return nil
+	})
+	checkPageAccounting(t, db)
+}
+
+func TestMmapOptions(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, &Options{Mmap: true, InitialMmapSize: 1 << 20})
+	if len(db.Pager.mmapData) != 1<<20 {
+		t.Fatalf("mapped %d bytes, want the initial mmap size %d", len(db.Pager.mmapData), 1<<20)
+	}
+	err := db.Update(func(tx *Tx) error {
+		for i := 0; i < 2000; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db = openTestDB(t, path, &Options{Mmap: true, ReadOnly: true})
+	defer db.Close()
+
+	db.View(func(tx *Tx) error {
+		v, err := tx.Get([]byte("k01999"))
+		if err != nil || string(v) != "v" {
+			t.Fatalf("Get(k01999) = %q, %v", v, err)
+		}
+		return nil
+	})
+}
@@ -4,30 +4,65 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"sort"
 )
 
 const (
 	// Node Types
-	NodeLeaf   = 1
-	NodeBranch = 2
+	NodeLeaf     = 1
+	NodeBranch   = 2
+	NodeOverflow = 3
+	NodeFreelist = 4
 
 	// Header sizes
-	NodeHeaderSize = 3
+	// The header holds the node type, the key count and a CRC32C checksum of the rest of the page.
+	ChecksumOffset = 3
+	ChecksumSize   = 4
+	NodeHeaderSize = ChecksumOffset + ChecksumSize
 
 	// Each offset is a uint16 (2 bytes), pointing to where the KV pair starts
 	OffsetSize = 2
 
-	// Inside a KV Pair, we store lengths first:
+	// Inside a KV Pair, we store lengths and entry flags first:
 	KeyLenSize   = 2
 	ValLenSize   = 2
-	KVHeaderSize = KeyLenSize + ValLenSize
+	FlagsSize    = 1
+	KVHeaderSize = KeyLenSize + ValLenSize + FlagsSize
+
+	// Entry flags
+	// The value of an overflow entry is a reference to a chain of overflow pages.
+	EntryFlagOverflow = 0x01
+	// The value of a bucket entry is the root page ID of the bucket's own tree.
+	EntryFlagBucket = 0x02
 )
 
+// Nodes never assume a page size: it is the length of their data, which is the page size of the database.
+
+// maxInlineValueSize returns the largest value stored in a leaf of the given page size.
+// Larger values are spilled into overflow pages, so a leaf always holds several entries.
+func maxInlineValueSize(pageSize int) int {
+	return pageSize / 4
+}
+
+// MaxKeySize returns the largest key that can be stored in a database of the given page size.
+// An entry with the largest key and the largest inline value takes at most half a page, so a full node
+// can always be split in two around a new entry, and the two branch entries of a new root always fit.
+func MaxKeySize(pageSize int) int {
+	return (pageSize-NodeHeaderSize)/2 - OffsetSize - KVHeaderSize - maxInlineValueSize(pageSize)
+}
+
 type Node struct {
 	data []byte
 }
 
+// kvPair is a detached copy of a single node entry.
+type kvPair struct {
+	key   []byte
+	val   []byte
+	flags byte
+}
+
 // getType returns the node type (NodeLeaf or NodeBranch) from the node header.
 func (n *Node) getType() uint16 {
 	return uint16(n.data[0])
@@ -38,6 +73,22 @@ func (n *Node) getKeyCount() uint16 {
 	return binary.LittleEndian.Uint16(n.data[1:3])
 }
 
+// setChecksum stores the checksum of the page contents in the node header.
+func (n *Node) setChecksum() {
+	binary.LittleEndian.PutUint32(n.data[ChecksumOffset:NodeHeaderSize], pageChecksum(n.data))
+}
+
+// verifyChecksum reports whether the checksum in the node header matches the page contents.
+func (n *Node) verifyChecksum() bool {
+	return binary.LittleEndian.Uint32(n.data[ChecksumOffset:NodeHeaderSize]) == pageChecksum(n.data)
+}
+
+// pageChecksum computes the CRC32C of a page, skipping the checksum field itself.
+func pageChecksum(data []byte) uint32 {
+	sum := crc32.Checksum(data[:ChecksumOffset], crcTable)
+	return crc32.Update(sum, crcTable, data[NodeHeaderSize:])
+}
+
 // getOffset returns the byte offset in the node data where the key-value pair at the given index is stored.
 func (n *Node) getOffset(index uint16) uint16 {
 	pos := NodeHeaderSize + OffsetSize*int(index)
@@ -45,13 +96,10 @@ func (n *Node) getOffset(index uint16) uint16 {
 }
 
 // getLeafKeyValue retrieves the key and value at the given index from the node.
+// Nodes read from disk are validated first, so the entry is known to lie inside the page.
 func (n *Node) getLeafKeyValue(index uint16) ([]byte, []byte) {
 	offset := int(n.getOffset(index))
 
-	if offset+KVHeaderSize > len(n.data) {
-		panic(fmt.Errorf("CORRUPTION: invalid offset %d for index %d: exceeds page size", offset, index))
-	}
-
 	keyLen := int(binary.LittleEndian.Uint16(n.data[offset : offset+KeyLenSize]))
 	valLen := int(binary.LittleEndian.Uint16(n.data[offset+KeyLenSize : offset+KVHeaderSize]))
 
@@ -59,18 +107,21 @@ func (n *Node) getLeafKeyValue(index uint16) ([]byte, []byte) {
 	keyEnd := start + keyLen
 	valEnd := keyEnd + valLen
 
-	if valEnd > len(n.data) {
-		panic(fmt.Errorf("CORRUPTION: data at index %d extends beyond page: offset=%d, keyLen=%d, valLen=%d, valEnd=%d, pageSize=%d", index, offset, keyLen, valLen, valEnd, len(n.data)))
-	}
-
 	return n.data[start:keyEnd], n.data[keyEnd:valEnd]
 }
 
-// writeLeafKeyValue writes a key-value pair to the node at the specified index and offset.
-func (n *Node) writeLeafKeyValue(index uint16, offset uint16, key []byte, val []byte) {
+// getFlags returns the entry flags of the key-value pair at the given index.
+func (n *Node) getFlags(index uint16) byte {
+	offset := int(n.getOffset(index))
+	return n.data[offset+KeyLenSize+ValLenSize]
+}
+
+// writeLeafKeyValue writes a key-value pair and its entry flags to the node at the specified index and offset.
+// Returns ErrNodeFull if the entry would extend past the end of the page.
+func (n *Node) writeLeafKeyValue(index uint16, offset uint16, key []byte, val []byte, flags byte) error {
 	requiredSpace := KVHeaderSize + len(key) + len(val)
 	if int(offset)+requiredSpace > len(n.data) {
-		panic(fmt.Errorf("write overflow: trying to write %d bytes at offset %d, page size %d", requiredSpace, offset, len(n.data)))
+		return fmt.Errorf("%w: %d byte entry at offset %d does not fit in a %d byte page", ErrNodeFull, requiredSpace, offset, len(n.data))
 	}
 
 	offsetPos := int(NodeHeaderSize + index*OffsetSize)
@@ -78,13 +129,15 @@ func (n *Node) writeLeafKeyValue(index uint16, offset uint16, key []byte, val []
 
 	dataPos := int(offset)
 	binary.LittleEndian.PutUint16(n.data[dataPos:dataPos+KeyLenSize], uint16(len(key)))
-	binary.LittleEndian.PutUint16(n.data[dataPos+KeyLenSize:dataPos+KVHeaderSize], uint16(len(val)))
+	binary.LittleEndian.PutUint16(n.data[dataPos+KeyLenSize:dataPos+KeyLenSize+ValLenSize], uint16(len(val)))
+	n.data[dataPos+KeyLenSize+ValLenSize] = flags
 
 	keyStart := dataPos + KVHeaderSize
 	valStart := keyStart + len(key)
 
 	copy(n.data[keyStart:valStart], key)
 	copy(n.data[valStart:valStart+len(val)], val)
+	return nil
 }
 
 // findKeyInNode performs a binary search to find the insertion index for the key.
@@ -107,23 +160,53 @@ func (n *Node) findKeyInNode(key []byte) (uint16, bool) {
 	return uint16(index), found
 }
 
+// findChildIndex returns the index of the branch entry whose subtree may contain the key.
+// In a branch node, if the key at index is strictly greater than search key,
+// we need to step back one index to get the correct child.
+func (n *Node) findChildIndex(key []byte) uint16 {
+	index, found := n.findKeyInNode(key)
+	if !found && index > 0 {
+		index--
+	}
+	return index
+}
+
 // getChild extracts the child page ID from a branch node entry at the given index.
 func (n *Node) getChild(index uint16) int {
 	_, pageID := n.getLeafKeyValue(index)
 	return int(binary.LittleEndian.Uint64(pageID))
 }
 
+// setChild points the branch entry at the given index to another child page.
+func (n *Node) setChild(index uint16, pageID int) {
+	_, pageIDBytes := n.getLeafKeyValue(index)
+	binary.LittleEndian.PutUint64(pageIDBytes, uint64(pageID))
+}
+
+// clearFirstKey replaces the first key of a branch node with an empty key, which sorts before every other key.
+func (n *Node) clearFirstKey() error {
+	pairs := n.getEntries()
+	pairs[0].key = nil
+
+	node, err := newNodeFromEntries(len(n.data), n.getType(), pairs)
+	if err != nil {
+		return err
+	}
+	n.data = node.data
+	return nil
+}
+
 // insertLeafKeyValue inserts a key-value pair into a leaf node, handling fragmentation by compacting if necessary.
-func (n *Node) insertLeafKeyValue(key []byte, value []byte) error {
+func (n *Node) insertLeafKeyValue(key []byte, value []byte, flags byte) error {
 	index, found := n.findKeyInNode(key)
 	if found {
-		return fmt.Errorf("key already exists")
+		return ErrKeyExists
 	}
 
 	count := n.getKeyCount()
 	newEntrySize := KVHeaderSize + len(key) + len(value)
 
-	heapStart := PageSize
+	heapStart := len(n.data)
 	maxEnd := 0
 
 	for i := uint16(0); i < count; i++ {
@@ -150,15 +233,15 @@ func (n *Node) insertLeafKeyValue(key []byte, value []byte) error {
 		maxEnd = offsetTableEnd
 	}
 
-	if offsetTableEnd > heapStart || maxEnd+newEntrySize > PageSize {
+	if offsetTableEnd > heapStart || maxEnd+newEntrySize > len(n.data) {
 		newEnd, ok := n.compact(true)
 		if !ok {
-			return fmt.Errorf("node is full")
+			return ErrNodeFull
 		}
-		maxEnd = int(newEnd)
+		maxEnd = newEnd
 
-		if maxEnd+newEntrySize > PageSize {
-			return fmt.Errorf("node is full")
+		if maxEnd+newEntrySize > len(n.data) {
+			return ErrNodeFull
 		}
 	}
 
@@ -167,91 +250,20 @@ func (n *Node) insertLeafKeyValue(key []byte, value []byte) error {
 	offsetPos := NodeHeaderSize + int(index)*OffsetSize
 	copy(n.data[offsetPos+OffsetSize:], n.data[offsetPos:NodeHeaderSize+int(count)*OffsetSize])
 
-	n.writeLeafKeyValue(index, uint16(writePos), key, value)
+	if err := n.writeLeafKeyValue(index, uint16(writePos), key, value, flags); err != nil {
+		return err
+	}
 
 	binary.LittleEndian.PutUint16(n.data[1:3], count+1)
 
 	return nil
 }
 
-// splitLeaf splits a full leaf node in half, moving the upper half to newNode and returning the promoted key.
-func (n *Node) splitLeaf(newNode *Node) []byte {
-	count := n.getKeyCount()
-	middle := count / 2
-
-	firstKey, _ := n.getLeafKeyValue(middle)
-	promoteKey := make([]byte, len(firstKey))
-	copy(promoteKey, firstKey)
-
-	if len(newNode.data) < PageSize {
-		newNode.data = make([]byte, PageSize)
-	}
-	newNode.data[0] = byte(NodeLeaf)
-	binary.LittleEndian.PutUint16(newNode.data[1:3], 0)
-
-	newCount := count - middle
-
-	newNodeDataOffset := NodeHeaderSize + int(newCount)*OffsetSize
-
-	for i := uint16(0); i < newCount; i++ {
-		oldIndex := middle + i
-		key, value := n.getLeafKeyValue(oldIndex)
-
-		newNode.writeLeafKeyValue(i, uint16(newNodeDataOffset), key, value)
-
-		entrySize := KVHeaderSize + len(key) + len(value)
-		newNodeDataOffset += entrySize
-	}
-
-	binary.LittleEndian.PutUint16(newNode.data[1:3], newCount)
-	binary.LittleEndian.PutUint16(n.data[1:3], middle)
-
-	n.compact(false)
-
-	return promoteKey
-}
-
-// splitBranch splits a full branch node in half, moving the upper half to newNode and returning the promoted key.
-func (n *Node) splitBranch(newNode *Node) []byte {
-	count := n.getKeyCount()
-	middle := count / 2
-
-	promoteKey, _ := n.getLeafKeyValue(middle)
-	promoteKeyCopy := make([]byte, len(promoteKey))
-	copy(promoteKeyCopy, promoteKey)
-
-	if len(newNode.data) < PageSize {
-		newNode.data = make([]byte, PageSize)
-	}
-	newNode.data[0] = byte(NodeBranch)
-	binary.LittleEndian.PutUint16(newNode.data[1:3], 0)
-
-	newCount := count - middle
-	newNodeDataOffset := NodeHeaderSize + int(newCount)*OffsetSize
-
-	for i := uint16(0); i < newCount; i++ {
-		oldIndex := middle + i
-		key, rawVal := n.getLeafKeyValue(oldIndex)
-
-		newNode.writeLeafKeyValue(i, uint16(newNodeDataOffset), key, rawVal)
-
-		entrySize := KVHeaderSize + len(key) + len(rawVal)
-		newNodeDataOffset += entrySize
-	}
-
-	binary.LittleEndian.PutUint16(newNode.data[1:3], newCount)
-	binary.LittleEndian.PutUint16(n.data[1:3], middle)
-
-	n.compact(false)
-
-	return promoteKeyCopy
-}
-
 // insertBranchKey inserts a key and associated child page ID into a branch node, handling fragmentation by compacting if necessary.
 func (n *Node) insertBranchKey(key []byte, childPageID int) error {
 	index, found := n.findKeyInNode(key)
 	if found {
-		return fmt.Errorf("key already exists in branch")
+		return fmt.Errorf("%w in branch", ErrKeyExists)
 	}
 
 	count := n.getKeyCount()
@@ -261,7 +273,7 @@ func (n *Node) insertBranchKey(key []byte, childPageID int) error {
 
 	newEntrySize := KVHeaderSize + len(key) + len(pageIDBytes)
 
-	heapStart := PageSize
+	heapStart := len(n.data)
 	maxEnd := 0
 
 	for i := uint16(0); i < count; i++ {
@@ -288,22 +300,24 @@ func (n *Node) insertBranchKey(key []byte, childPageID int) error {
 		maxEnd = offsetTableEnd
 	}
 
-	if offsetTableEnd > heapStart || maxEnd+newEntrySize > PageSize {
+	if offsetTableEnd > heapStart || maxEnd+newEntrySize > len(n.data) {
 		newEnd, ok := n.compact(true)
 		if !ok {
-			return fmt.Errorf("node is full")
+			return ErrNodeFull
 		}
-		maxEnd = int(newEnd)
+		maxEnd = newEnd
 
-		if maxEnd+newEntrySize > PageSize {
-			return fmt.Errorf("node is full")
+		if maxEnd+newEntrySize > len(n.data) {
+			return ErrNodeFull
 		}
 	}
 
 	offsetPos := NodeHeaderSize + int(index)*OffsetSize
 	copy(n.data[offsetPos+OffsetSize:], n.data[offsetPos:NodeHeaderSize+int(count)*OffsetSize])
 
-	n.writeLeafKeyValue(index, uint16(maxEnd), key, pageIDBytes)
+	if err := n.writeLeafKeyValue(index, uint16(maxEnd), key, pageIDBytes, 0); err != nil {
+		return err
+	}
 
 	binary.LittleEndian.PutUint16(n.data[1:3], count+1)
 
@@ -313,28 +327,17 @@ func (n *Node) insertBranchKey(key []byte, childPageID int) error {
 // compact rewrites the node's data to be perfectly contiguous.
 // If reserveNewEntry is true, it leaves a gap for one additional offset in the offset table.
 // Returns the offset where the next data entry should be written, and a bool indicating success.
-func (n *Node) compact(reserveNewEntry bool) (uint16, bool) {
+// The offset is an int because a full 64KB page ends past the range of uint16.
+func (n *Node) compact(reserveNewEntry bool) (int, bool) {
 	count := n.getKeyCount()
 	if count == 0 {
 		if reserveNewEntry {
-			return uint16(NodeHeaderSize + OffsetSize), true
+			return NodeHeaderSize + OffsetSize, true
 		}
-		return uint16(NodeHeaderSize), true
+		return NodeHeaderSize, true
 	}
 
-	type kv struct {
-		key []byte
-		val []byte
-	}
-	pairs := make([]kv, count)
-	for i := uint16(0); i < count; i++ {
-		key, val := n.getLeafKeyValue(i)
-		k := make([]byte, len(key))
-		v := make([]byte, len(val))
-		copy(k, key)
-		copy(v, val)
-		pairs[i] = kv{k, v}
-	}
+	pairs := n.getEntries()
 
 	offsetCount := int(count)
 	if reserveNewEntry {
@@ -348,7 +351,7 @@ func (n *Node) compact(reserveNewEntry bool) (uint16, bool) {
 		totalSize += KVHeaderSize + len(p.key) + len(p.val)
 	}
 
-	if totalSize > PageSize {
+	if totalSize > len(n.data) {
 		return 0, false
 	}
 
@@ -356,18 +359,191 @@ func (n *Node) compact(reserveNewEntry bool) (uint16, bool) {
 	for i := uint16(0); i < count; i++ {
 		pair := pairs[i]
 
-		binary.LittleEndian.PutUint16(n.data[NodeHeaderSize+int(i)*OffsetSize:], uint16(currentPos))
+		if err := n.writeLeafKeyValue(i, uint16(currentPos), pair.key, pair.val, pair.flags); err != nil {
+			return 0, false
+		}
+		currentPos += KVHeaderSize + len(pair.key) + len(pair.val)
+	}
+
+	return currentPos, true
+}
+
+// getEntries returns copies of all entries stored in the node, in key order.
+func (n *Node) getEntries() []kvPair {
+	count := n.getKeyCount()
+	pairs := make([]kvPair, count)
+	for i := uint16(0); i < count; i++ {
+		key, val := n.getLeafKeyValue(i)
+		k := make([]byte, len(key))
+		v := make([]byte, len(val))
+		copy(k, key)
+		copy(v, val)
+		pairs[i] = kvPair{k, v, n.getFlags(i)}
+	}
+	return pairs
+}
+
+// removeKeyValue removes the entry at the given index from the offset table.
+// The entry's bytes stay in the heap until the next compaction reclaims them.
+func (n *Node) removeKeyValue(index uint16) {
+	count := n.getKeyCount()
+	offsetPos := NodeHeaderSize + int(index)*OffsetSize
+	copy(n.data[offsetPos:], n.data[offsetPos+OffsetSize:NodeHeaderSize+int(count)*OffsetSize])
+	binary.LittleEndian.PutUint16(n.data[1:3], count-1)
+}
 
-		binary.LittleEndian.PutUint16(n.data[currentPos:], uint16(len(pair.key)))
-		currentPos += 2
-		binary.LittleEndian.PutUint16(n.data[currentPos:], uint16(len(pair.val)))
-		currentPos += 2
+// usedBytes returns the number of bytes taken by the header, the offset table and the live entries.
+func (n *Node) usedBytes() int {
+	count := n.getKeyCount()
+	size := NodeHeaderSize
+	for i := uint16(0); i < count; i++ {
+		key, val := n.getLeafKeyValue(i)
+		size += OffsetSize + KVHeaderSize + len(key) + len(val)
+	}
+	return size
+}
+
+// fragmentedBytes returns the number of bytes in the node's heap taken by removed entries,
+// which are only reclaimed when the node is compacted.
+func (n *Node) fragmentedBytes() int {
+	count := n.getKeyCount()
+	if count == 0 {
+		return 0
+	}
 
-		copy(n.data[currentPos:], pair.key)
-		currentPos += len(pair.key)
-		copy(n.data[currentPos:], pair.val)
-		currentPos += len(pair.val)
+	heapStart, heapEnd, live := len(n.data), 0, 0
+	for i := uint16(0); i < count; i++ {
+		offset := int(n.getOffset(i))
+		key, val := n.getLeafKeyValue(i)
+		size := KVHeaderSize + len(key) + len(val)
+
+		heapStart = min(heapStart, offset)
+		heapEnd = max(heapEnd, offset+size)
+		live += size
 	}
 
-	return uint16(currentPos), true
+	return heapEnd - heapStart - live
+}
+
+// underflow reports whether the node is too sparse and should be rebalanced with a sibling.
+func (n *Node) underflow() bool {
+	minKeys := uint16(1)
+	if n.getType() == NodeBranch {
+		minKeys = 2
+	}
+	// Nodes whose live entries occupy less than a quarter of the page borrow from or merge with a sibling
+	return n.getKeyCount() < minKeys || n.usedBytes() < len(n.data)/4
+}
+
+// entriesSize returns the number of bytes a node holding exactly these entries would need.
+func entriesSize(pairs []kvPair) int {
+	size := NodeHeaderSize
+	for _, p := range pairs {
+		size += OffsetSize + KVHeaderSize + len(p.key) + len(p.val)
+	}
+	return size
+}
+
+// splitIndex returns the index at which to split the entries into two nodes of roughly equal size.
+// The split always leaves at least one entry on each side.
+func splitIndex(pairs []kvPair) int {
+	total := entriesSize(pairs)
+	best, bestSize := 1, total
+
+	left := NodeHeaderSize
+	for i := 1; i < len(pairs); i++ {
+		p := pairs[i-1]
+		left += OffsetSize + KVHeaderSize + len(p.key) + len(p.val)
+		right := total - left + NodeHeaderSize
+		if size := max(left, right); size < bestSize {
+			best, bestSize = i, size
+		}
+	}
+	return best
+}
+
+// newNodeFromEntries builds a fresh, compacted node of the given type and page size holding the entries.
+// Returns ErrNodeFull if the entries don't fit in a single page.
+func newNodeFromEntries(pageSize int, nodeType uint16, pairs []kvPair) (*Node, error) {
+	n := &Node{data: make([]byte, pageSize)}
+	n.data[0] = byte(nodeType)
+	binary.LittleEndian.PutUint16(n.data[1:3], uint16(len(pairs)))
+
+	if NodeHeaderSize+len(pairs)*OffsetSize > pageSize {
+		return nil, fmt.Errorf("%w: offset table of %d entries does not fit in a %d byte page", ErrNodeFull, len(pairs), pageSize)
+	}
+
+	dataPos := NodeHeaderSize + len(pairs)*OffsetSize
+	for i, p := range pairs {
+		if err := n.writeLeafKeyValue(uint16(i), uint16(dataPos), p.key, p.val, p.flags); err != nil {
+			return nil, err
+		}
+		dataPos += KVHeaderSize + len(p.key) + len(p.val)
+	}
+	return n, nil
+}
+
+// validate checks that a tree node read from disk can be used safely: it is a leaf or a branch, its offset
+// table and every entry lie inside the page, branch entries hold child page IDs and leaf entries hold values
+// that match their flags. Problems are reported as a *CorruptionError pointing at the offending bytes.
+func (n *Node) validate(pageID int) error {
+	corrupt := func(offset int, format string, args ...any) error {
+		return &CorruptionError{PageID: pageID, Offset: offset, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	nodeType := n.getType()
+	if nodeType != NodeLeaf && nodeType != NodeBranch {
+		return corrupt(0, "tree page has node type %d", nodeType)
+	}
+
+	count := int(n.getKeyCount())
+	if nodeType == NodeBranch && count == 0 {
+		return corrupt(1, "branch has no children")
+	}
+
+	tableEnd := NodeHeaderSize + count*OffsetSize
+	if tableEnd > len(n.data) {
+		return corrupt(1, "offset table of %d entries exceeds the page", count)
+	}
+
+	for i := 0; i < count; i++ {
+		offset := int(n.getOffset(uint16(i)))
+		if offset < tableEnd || offset+KVHeaderSize > len(n.data) {
+			return corrupt(NodeHeaderSize+i*OffsetSize, "entry %d has invalid offset %d", i, offset)
+		}
+
+		keyLen := int(binary.LittleEndian.Uint16(n.data[offset : offset+KeyLenSize]))
+		valLen := int(binary.LittleEndian.Uint16(n.data[offset+KeyLenSize : offset+KeyLenSize+ValLenSize]))
+		if end := offset + KVHeaderSize + keyLen + valLen; end > len(n.data) {
+			return corrupt(offset, "entry %d ends at %d, past the end of the page", i, end)
+		}
+
+		flags := n.data[offset+KeyLenSize+ValLenSize]
+		switch {
+		case nodeType == NodeBranch && valLen != 8:
+			return corrupt(offset, "branch entry %d has a %d byte child pointer", i, valLen)
+		case nodeType == NodeBranch && flags != 0:
+			return corrupt(offset, "branch entry %d has entry flags %#x", i, flags)
+		case flags == EntryFlagBucket && valLen != 8:
+			return corrupt(offset, "bucket entry %d has a %d byte root pointer", i, valLen)
+		case flags == EntryFlagOverflow && valLen != overflowRefSize:
+			return corrupt(offset, "overflow entry %d has a %d byte reference", i, valLen)
+		case flags&^(EntryFlagBucket|EntryFlagOverflow) != 0 || flags == EntryFlagBucket|EntryFlagOverflow:
+			return corrupt(offset, "entry %d has unknown entry flags %#x", i, flags)
+		}
+	}
+
+	return nil
+}
+
+// encodePageID encodes a page ID the way branch and bucket entries store it.
+func encodePageID(pageID int) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(pageID))
+	return buf
+}
+
+// cloneBytes returns a copy of b, so callers can reuse their buffers.
+func cloneBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
 }
@@ -0,0 +1,94 @@
+package gokv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestNodeChecksum(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("a"), []byte("1")) }); err != nil {
+		t.Fatal(err)
+	}
+	root := db.Root
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte{0x42}, int64(root*DefaultPageSize+100)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	db = openTestDB(t, path, nil)
+	defer db.Close()
+
+	err = db.View(func(tx *Tx) error {
+		_, err := tx.Get([]byte("a"))
+		return err
+	})
+	var ce *CorruptionError
+	if !errors.As(err, &ce) || ce.PageID != root {
+		t.Fatalf("Get from a page with a flipped byte = %v, want a CorruptionError for page %d", err, root)
+	}
+}
+
+func TestMaxKeySize(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	maxKey := MaxKeySize(db.Pager.PageSize())
+	err := db.Update(func(tx *Tx) error {
+		if err := tx.Put([]byte("x"), []byte("v")); err != nil {
+			return err
+		}
+
+		// A rejected key leaves the transaction as it was
+		root, freed, allocated := tx.root, len(tx.freed), len(tx.allocated)
+		if err := tx.Put(bytes.Repeat([]byte("b"), maxKey+1), bytes.Repeat([]byte("v"), 3000)); !errors.Is(err, ErrKeyTooLarge) {
+			t.Errorf("Put with a key of MaxKeySize+1 = %v, want ErrKeyTooLarge", err)
+		}
+		if _, err := tx.CreateBucket(bytes.Repeat([]byte("c"), maxKey+1)); !errors.Is(err, ErrKeyTooLarge) {
+			t.Errorf("CreateBucket with a name of MaxKeySize+1 = %v, want ErrKeyTooLarge", err)
+		}
+		if tx.root != root || len(tx.freed) != freed || len(tx.allocated) != allocated {
+			t.Errorf("rejected keys changed the transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nodes full of the largest keys with the largest inline values still split
+	for r := 0; r < 3; r++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 40; i++ {
+				k := bytes.Repeat([]byte{byte('d' + r)}, maxKey)
+				copy(k, fmt.Sprintf("%05d", i))
+				if err := tx.Put(k, bytes.Repeat([]byte("v"), maxInlineValueSize(tx.pageSize()))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db.View(func(tx *Tx) error {
+		if errs := tx.Check(); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		return nil
+	})
+}
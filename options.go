@@ -0,0 +1,68 @@
+package gokv
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Options configures how OpenWithOptions and OpenStorage open a database. A nil *Options uses DefaultOptions.
+type Options struct {
+	// ReadOnly opens the file read-only. Update and writable transactions fail, and the file must already exist.
+	ReadOnly bool
+
+	// Timeout is how long to wait for another process to release its lock on the file.
+	// Read-write handles lock the file exclusively and read-only handles share the lock.
+	// 0 fails with ErrDatabaseLocked right away.
+	Timeout time.Duration
+
+	// FileMode is the permission of the file when it is created. Defaults to 0600.
+	FileMode os.FileMode
+
+	// NoSync skips the fsync calls of every commit. A crash can lose or corrupt recent commits,
+	// so it is only meant for bulk loads that can be redone from scratch.
+	NoSync bool
+
+	// NoGrowSync skips the fsync after the file is grown ahead of time.
+	NoGrowSync bool
+
+	// PageSize is the page size of a newly created database: a power of two from MinPageSize to MaxPageSize.
+	// Defaults to DefaultPageSize. An existing database keeps the page size it was created with.
+	PageSize int
+
+	// Mmap reads pages through a read-only memory mapping of the file instead of ReadAt (Unix only).
+	// It is only available for a FileStorage.
+	Mmap bool
+
+	// InitialMmapSize preallocates the file to at least this many bytes on open and, with Mmap,
+	// maps that much up front, so a growing database is not remapped while it is small.
+	InitialMmapSize int
+}
+
+// DefaultOptions are the options used by Open.
+var DefaultOptions = &Options{
+	FileMode: 0600,
+}
+
+// validate checks the options for values and combinations that cannot work.
+func (o *Options) validate() error {
+	if o.FileMode&^os.ModePerm != 0 {
+		return fmt.Errorf("invalid file mode %v: only permission bits are allowed", o.FileMode)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("invalid lock timeout %v", o.Timeout)
+	}
+	if o.PageSize != 0 && !validPageSize(o.PageSize) {
+		return fmt.Errorf("invalid page size %d: must be a power of two from %d to %d", o.PageSize, MinPageSize, MaxPageSize)
+	}
+	if o.InitialMmapSize < 0 {
+		return fmt.Errorf("invalid initial mmap size %d", o.InitialMmapSize)
+	}
+	if o.ReadOnly && o.InitialMmapSize > 0 {
+		return fmt.Errorf("initial mmap size cannot be used with a read-only database, the file cannot be grown")
+	}
+	if o.ReadOnly && (o.NoSync || o.NoGrowSync) {
+		return fmt.Errorf("sync options cannot be used with a read-only database, it never writes")
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOptionsValidation(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		options *Options
+	}{
+		{"negative initial mmap size", &Options{InitialMmapSize: -1}},
+		{"file mode with type bits", &Options{FileMode: os.ModeDir | 0600}},
+		{"negative timeout", &Options{Timeout: -1}},
+		{"page size not a power of two", &Options{PageSize: 3000}},
+		{"read-only with initial mmap size", &Options{ReadOnly: true, InitialMmapSize: 1 << 20}},
+		{"read-only with no sync", &Options{ReadOnly: true, NoSync: true}},
+		{"read-only with no grow sync", &Options{ReadOnly: true, NoGrowSync: true}},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "test.db")
+		if db, err := OpenWithOptions(path, tt.options); err == nil {
+			db.Close()
+			t.Errorf("%s: opened without an error", tt.name)
+		}
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: invalid options created the file", tt.name)
+		}
+	}
+}
+
+func TestOptionsCreate(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, &Options{FileMode: 0640, NoSync: true, InitialMmapSize: 1 << 20})
+	defer db.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("file mode %v, want 0640", info.Mode().Perm())
+	}
+	if info.Size() != 1<<20 {
+		t.Errorf("file size %d, want it preallocated to %d", info.Size(), 1<<20)
+	}
+}
+
+func TestOptionsReadOnly(t *testing.T) {
+	path := tempDBPath(t)
+	if _, err := OpenWithOptions(path, &Options{ReadOnly: true}); err == nil {
+		t.Fatal("opened a missing file read-only")
+	}
+
+	db := openTestDB(t, path, nil)
+	err := db.Update(func(tx *Tx) error {
+		for i := 0; i < 2000; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db = openTestDB(t, path, &Options{ReadOnly: true})
+	defer db.Close()
+
+	if err := db.Update(func(tx *Tx) error { return nil }); !errors.Is(err, ErrDatabaseReadOnly) {
+		t.Fatalf("Update on a read-only database = %v, want ErrDatabaseReadOnly", err)
+	}
+	db.View(func(tx *Tx) error {
+		v, err := tx.Get([]byte("k01999"))
+		if err != nil || string(v) != "v" {
+			t.Fatalf("Get(k01999) = %q, %v", v, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,149 @@
+package gokv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// MaxValueSize is the largest value that can be stored, limited by the 32-bit length in overflow references.
+const MaxValueSize = math.MaxUint32
+
+const (
+	// An overflow page stores the number of value bytes it holds in the key count field,
+	// followed by the page ID of the next page in the chain (0 ends the chain).
+	OverflowNextSize   = 4
+	OverflowHeaderSize = NodeHeaderSize + OverflowNextSize
+
+	// A leaf entry for a spilled value holds the first page of the chain and the total value length
+	overflowRefSize = 12
+)
+
+// writeOverflow spills a value into a chain of freshly allocated overflow pages
+// and returns the reference to store in the leaf in place of the value.
+func (tx *Tx) writeOverflow(value []byte) []byte {
+	chunkSize := tx.pageSize() - OverflowHeaderSize
+	pageCount := (len(value) + chunkSize - 1) / chunkSize
+
+	pageIDs := make([]int, pageCount)
+	for i := range pageIDs {
+		pageIDs[i] = tx.allocateNode()
+	}
+
+	for i, pageID := range pageIDs {
+		start := i * chunkSize
+		end := min(start+chunkSize, len(value))
+
+		next := 0
+		if i+1 < pageCount {
+			next = pageIDs[i+1]
+		}
+
+		tx.dirtyNodes[pageID] = newOverflowNode(tx.pageSize(), value[start:end], next)
+	}
+
+	return encodeOverflowRef(pageIDs[0], len(value))
+}
+
+// newOverflowNode builds an overflow page holding a chunk of a value and the page ID of the next page in the chain.
+func newOverflowNode(pageSize int, chunk []byte, next int) *Node {
+	node := &Node{data: make([]byte, pageSize)}
+	node.data[0] = byte(NodeOverflow)
+	binary.LittleEndian.PutUint16(node.data[1:3], uint16(len(chunk)))
+	binary.LittleEndian.PutUint32(node.data[NodeHeaderSize:OverflowHeaderSize], uint32(next))
+	copy(node.data[OverflowHeaderSize:], chunk)
+	return node
+}
+
+// readOverflow reassembles a value from the overflow chain the reference points to.
+func (tx *Tx) readOverflow(ref []byte) ([]byte, error) {
+	pageID, length, err := decodeOverflowRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	value := make([]byte, 0, length)
+	for len(value) < length {
+		if pageID == 0 {
+			return nil, fmt.Errorf("overflow chain ended after %d of %d bytes", len(value), length)
+		}
+
+		node, err := tx.getOverflowNode(pageID)
+		if err != nil {
+			return nil, err
+		}
+
+		chunkLen := int(node.getKeyCount())
+		if len(value)+chunkLen > length {
+			return nil, &CorruptionError{PageID: pageID, Offset: 1, Reason: fmt.Sprintf("overflow page holds %d bytes, past the %d byte value", chunkLen, length)}
+		}
+
+		value = append(value, node.data[OverflowHeaderSize:OverflowHeaderSize+chunkLen]...)
+		pageID = node.getOverflowNext()
+	}
+
+	return value, nil
+}
+
+// freeOverflow releases every page of the overflow chain the reference points to.
+func (tx *Tx) freeOverflow(ref []byte) error {
+	pageID, _, err := decodeOverflowRef(ref)
+	if err != nil {
+		return err
+	}
+
+	for pageID != 0 {
+		node, err := tx.getOverflowNode(pageID)
+		if err != nil {
+			return err
+		}
+
+		next := node.getOverflowNext()
+		tx.freePage(pageID)
+		pageID = next
+	}
+
+	return nil
+}
+
+// getOverflowNode reads a page of an overflow chain and checks its type and the length of the chunk it holds.
+func (tx *Tx) getOverflowNode(pageID int) (*Node, error) {
+	node, _, err := tx.getPage(pageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overflow page %d: %w", pageID, err)
+	}
+
+	if node.getType() != NodeOverflow {
+		return nil, &CorruptionError{PageID: pageID, Offset: 0, Reason: fmt.Sprintf("page has node type %d, expected an overflow page", node.getType())}
+	}
+
+	if chunkLen := int(node.getKeyCount()); OverflowHeaderSize+chunkLen > len(node.data) {
+		return nil, &CorruptionError{PageID: pageID, Offset: 1, Reason: fmt.Sprintf("overflow page holds an invalid %d bytes", chunkLen)}
+	}
+
+	return node, nil
+}
+
+// getOverflowNext returns the page ID of the next page in an overflow chain, or 0 at the end of the chain.
+func (n *Node) getOverflowNext() int {
+	return int(binary.LittleEndian.Uint32(n.data[NodeHeaderSize:OverflowHeaderSize]))
+}
+
+// encodeOverflowRef builds the reference to an overflow chain stored in a leaf entry.
+func encodeOverflowRef(pageID int, length int) []byte {
+	ref := make([]byte, overflowRefSize)
+	binary.LittleEndian.PutUint64(ref[0:8], uint64(pageID))
+	binary.LittleEndian.PutUint32(ref[8:12], uint32(length))
+	return ref
+}
+
+// decodeOverflowRef splits an overflow reference into the first page ID of the chain and the value length.
+func decodeOverflowRef(ref []byte) (int, int, error) {
+	if len(ref) != overflowRefSize {
+		return 0, 0, fmt.Errorf("invalid overflow reference of %d bytes", len(ref))
+	}
+
+	pageID := int(binary.LittleEndian.Uint64(ref[0:8]))
+	length := int(binary.LittleEndian.Uint32(ref[8:12]))
+	return pageID, length, nil
+}
@@ -0,0 +1,80 @@
+package gokv
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestOverflow(t *testing.T) {
+	path := tempDBPath(t)
+	db := openTestDB(t, path, nil)
+	defer func() { db.Close() }()
+
+	rng := rand.New(rand.NewSource(3))
+	want := map[string]string{}
+
+	check := func(round int) {
+		t.Helper()
+
+		db.View(func(tx *Tx) error {
+			for k, v := range want {
+				got, err := tx.Get([]byte(k))
+				if err != nil || string(got) != v {
+					t.Fatalf("round %d: Get(%s) returned %d bytes, %v, want %d bytes", round, k, len(got), err, len(v))
+				}
+			}
+
+			n := 0
+			c := tx.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				if want[string(k)] != string(v) {
+					t.Fatalf("round %d: cursor value of %s does not match", round, k)
+				}
+				n++
+			}
+			if err := c.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if n != len(want) {
+				t.Fatalf("round %d: cursor visited %d keys, want %d", round, n, len(want))
+			}
+			return nil
+		})
+	}
+
+	for round := 0; round < 10; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 50; i++ {
+				k := fmt.Sprintf("k%04d", rng.Intn(200))
+				if _, ok := want[k]; ok {
+					if err := tx.Delete([]byte(k)); err != nil {
+						return err
+					}
+					delete(want, k)
+					continue
+				}
+
+				v := make([]byte, 1000+rng.Intn(20000))
+				rng.Read(v)
+				if err := tx.Put([]byte(k), v); err != nil {
+					return err
+				}
+				want[k] = string(v)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		check(round)
+		checkPageAccounting(t, db)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db = openTestDB(t, path, nil)
+	check(10)
+}
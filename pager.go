@@ -3,52 +3,136 @@ package gokv
 import (
 	"fmt"
 	"os"
+	"sync"
+	"time"
 )
 
-const PageSize = 4096
+const (
+	// DefaultPageSize is the page size of a new database unless Options.PageSize asks for another one.
+	DefaultPageSize = 4096
+
+	// Page sizes must be a power of two in this range. Node offsets are uint16, so pages can't be larger than 64KB.
+	MinPageSize = 4096
+	MaxPageSize = 65536
+)
 
 type Pager struct {
-	file      *os.File
-	freePages []int
-	numPages  int
+	storage       Storage
+	file          *os.File // the file behind storage when it is a FileStorage; only a file is locked and mapped
+	freePages     []int
+	freelistPages []int            // pages holding the committed free list
+	pending       map[uint64][]int // pages freed by a commit, keyed by its TxID, that readers may still reach
+	numPages      int
+	pageSize      int
+	cache         *pageCache // pages read from disk, when they are not served from the mapping
+
+	// When mmap is enabled, pages inside the mapping are read without copying. Read transactions
+	// hold mmapLock for reading while they use slices of the mapping, so it is only replaced when none are open.
+	mmapEnabled bool
+	mmapData    []byte
+	mmapLock    sync.RWMutex
+	minMmapSize int // the file is grown and mapped to at least this many bytes
+
+	readOnly   bool
+	noGrowSync bool // skip the fsync after growing the file
+
+	stats pagerStats
 }
 
 // NewPager creates a new pager instance for the given filename.
 func NewPager(filename string) (*Pager, error) {
-	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	return openPager(filename, DefaultOptions)
+}
+
+// openPager opens or creates the file according to the options and creates a pager for it.
+func openPager(filename string, options *Options) (*Pager, error) {
+	flag := os.O_RDWR | os.O_CREATE
+	if options.ReadOnly {
+		flag = os.O_RDONLY
+	}
+
+	file, err := os.OpenFile(filename, flag, options.FileMode)
 	if err != nil {
 		return nil, err
 	}
 
-	info, err := file.Stat()
+	pager, err := newPager(&FileStorage{File: file}, options)
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
+	return pager, nil
+}
+
+// newPager creates a pager for the storage. A FileStorage is locked against other processes first.
+func newPager(storage Storage, options *Options) (*Pager, error) {
+	var file *os.File
+	if fs, ok := storage.(*FileStorage); ok {
+		file = fs.File
+
+		// Keep other processes from writing the file while this handle uses it
+		err := flock(file, !options.ReadOnly, options.Timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	size, err := storage.Size()
+	if err != nil {
+		if file != nil {
+			funlock(file)
+		}
+		return nil, err
+	}
+
+	// An existing database keeps the page size stored in its meta page, which Open applies once it is read
+	pageSize := options.PageSize
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
 
 	// Initialize numPages based on current file size
 	return &Pager{
-		file:     file,
-		numPages: int(info.Size() / PageSize),
+		storage:     storage,
+		file:        file,
+		pending:     make(map[uint64][]int),
+		cache:       newPageCache(DefaultCacheSize),
+		numPages:    int(size) / pageSize,
+		pageSize:    pageSize,
+		readOnly:    options.ReadOnly,
+		noGrowSync:  options.NoGrowSync,
+		minMmapSize: options.InitialMmapSize,
 	}, nil
 }
 
 // Read reads a page from disk at the given page ID.
+// The returned buffer may be shared with the page cache or the mapping and must not be modified.
 func (p *Pager) Read(pageID int) ([]byte, error) {
-	offset := int64(pageID * PageSize)
+	offset := int64(pageID) * int64(p.pageSize)
+	end := offset + int64(p.pageSize)
+
+	if end <= int64(len(p.mmapData)) {
+		return p.mmapData[offset:end:end], nil
+	}
 
-	buff := make([]byte, PageSize)
+	if data, ok := p.cache.get(pageID); ok {
+		return data, nil
+	}
+
+	buff := make([]byte, p.pageSize)
 
-	_, err := p.file.ReadAt(buff, offset)
+	_, err := p.storage.ReadAt(buff, offset)
 	if err != nil {
 		return nil, err
 	}
 
+	p.cache.put(pageID, buff)
 	return buff, nil
 }
 
 // Write writes a page to disk at the given page ID.
 func (p *Pager) Write(pageID int, data []byte) error {
-	if len(data) > PageSize {
+	if len(data) > p.pageSize {
 		return fmt.Errorf("data too large for page")
 	}
 
@@ -58,19 +142,74 @@ func (p *Pager) Write(pageID int, data []byte) error {
 		p.numPages = pageID + 1
 	}
 
-	offset := int64(pageID * PageSize)
-	_, err := p.file.WriteAt(data, offset)
+	// The cached copy is stale from here on
+	p.cache.remove(pageID)
+
+	offset := int64(pageID) * int64(p.pageSize)
+	n, err := p.storage.WriteAt(data, offset)
+
+	p.stats.writeN.Add(1)
+	p.stats.bytesWritten.Add(int64(n))
 	return err
 }
 
+// SetCacheSize changes the memory budget of the page cache in bytes. 0 disables the cache.
+func (p *Pager) SetCacheSize(size int) {
+	p.cache.setMaxSize(size)
+}
+
+// CacheStats returns the hit and miss counters and the current size of the page cache.
+func (p *Pager) CacheStats() CacheStats {
+	return p.cache.stats()
+}
+
 // Sync flushes all pending writes to disk.
 func (p *Pager) Sync() error {
-	return p.file.Sync()
+	start := time.Now()
+	err := p.storage.Sync()
+
+	p.stats.syncN.Add(1)
+	p.stats.syncTime.Add(int64(time.Since(start)))
+	return err
 }
 
-// Close closes the pager's file handle.
+// Close unmaps and unlocks the file and closes the pager's storage.
+// It waits for open read transactions to release the mapping.
 func (p *Pager) Close() error {
-	return p.file.Close()
+	p.mmapLock.Lock()
+	defer p.mmapLock.Unlock()
+
+	if p.mmapData != nil {
+		if err := munmap(p.mmapData); err != nil {
+			return fmt.Errorf("failed to unmap database: %w", err)
+		}
+		p.mmapData = nil
+	}
+
+	if p.file != nil {
+		if err := funlock(p.file); err != nil {
+			return fmt.Errorf("failed to unlock database: %w", err)
+		}
+	}
+
+	return p.storage.Close()
+}
+
+// PageSize returns the size of the database's pages in bytes.
+func (p *Pager) PageSize() int {
+	return p.pageSize
+}
+
+// EnableMmap switches reads to a read-only memory mapping of the file, so nodes are read in place
+// instead of being copied into a fresh buffer. It must be called before any transaction is started.
+// Only a FileStorage can be mapped.
+func (p *Pager) EnableMmap() error {
+	if p.file == nil {
+		return fmt.Errorf("mmap is only supported for a FileStorage")
+	}
+
+	p.mmapEnabled = true
+	return p.remap()
 }
 
 // GetFreePage returns an available page ID, either from the free list or by extending the file.
@@ -92,3 +231,113 @@ func (p *Pager) GetFreePage() int {
 func (p *Pager) ReleasePage(pageID int) {
 	p.freePages = append(p.freePages, pageID)
 }
+
+// addPending records pages freed by the commit with the given TxID.
+// They are not handed out until releasePending is called with that TxID or a later one.
+func (p *Pager) addPending(txID uint64, pageIDs []int) {
+	if len(pageIDs) == 0 {
+		return
+	}
+	p.pending[txID] = append(p.pending[txID], pageIDs...)
+}
+
+// releasePending moves pages freed by commits up to and including txID to the free list.
+func (p *Pager) releasePending(txID uint64) {
+	for id, pageIDs := range p.pending {
+		if id <= txID {
+			p.freePages = append(p.freePages, pageIDs...)
+			delete(p.pending, id)
+		}
+	}
+}
+
+// remap maps the file again once the pages in use have grown past the current mapping.
+// The file is grown ahead of time to a doubling size, so remapping is rare.
+// The old mapping is only unmapped when no read transaction holds slices of it;
+// otherwise pages past its end keep being read with ReadAt, and a later commit tries again.
+// If remapping fails, the old mapping or none at all is left in place, so reads still work through ReadAt.
+func (p *Pager) remap() error {
+	if !p.mmapEnabled {
+		return nil
+	}
+
+	needed := p.numPages * p.pageSize
+	if needed <= len(p.mmapData) {
+		return nil
+	}
+
+	if !p.mmapLock.TryLock() {
+		return nil
+	}
+	defer p.mmapLock.Unlock()
+
+	size := max(mmapSize(needed), p.minMmapSize)
+
+	fileSize, err := p.storage.Size()
+	if err != nil {
+		return err
+	}
+
+	// A read-only file cannot be grown, so only map what is there
+	if p.readOnly {
+		size = int(fileSize) / p.pageSize * p.pageSize
+	} else if err := p.grow(size); err != nil {
+		return err
+	}
+
+	if p.mmapData != nil {
+		if err := munmap(p.mmapData); err != nil {
+			return fmt.Errorf("failed to unmap database: %w", err)
+		}
+		p.mmapData = nil
+	}
+
+	data, err := mmap(p.file, size)
+	if err != nil {
+		return fmt.Errorf("failed to map database: %w", err)
+	}
+	p.mmapData = data
+
+	return nil
+}
+
+// grow extends the file to at least size bytes, rounded up to whole pages, and syncs the new size
+// unless NoGrowSync is set. Pages past the committed page count are not part of the database.
+func (p *Pager) grow(size int) error {
+	size = (size + p.pageSize - 1) / p.pageSize * p.pageSize
+
+	fileSize, err := p.storage.Size()
+	if err != nil {
+		return err
+	}
+	if fileSize >= int64(size) {
+		return nil
+	}
+
+	if err := p.storage.Truncate(int64(size)); err != nil {
+		return fmt.Errorf("failed to grow database file: %w", err)
+	}
+
+	if !p.noGrowSync {
+		if err := p.Sync(); err != nil {
+			return fmt.Errorf("failed to sync database file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// mmapSize returns the size to map for at least needed bytes: doubling from 32KB up to 1GB,
+// then in 1GB steps.
+func mmapSize(needed int) int {
+	const maxStep = 1 << 30
+
+	size := 32 << 10
+	for size < needed && size < maxStep {
+		size *= 2
+	}
+	if size < needed {
+		size = (needed + maxStep - 1) / maxStep * maxStep
+	}
+	return size
+}
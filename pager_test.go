@@ -0,0 +1,89 @@
+package gokv
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPageSizes(t *testing.T) {
+	for _, pageSize := range []int{MinPageSize, 16384, 65536} {
+		t.Run(fmt.Sprint(pageSize), func(t *testing.T) {
+			path := tempDBPath(t)
+			db := openTestDB(t, path, &Options{PageSize: pageSize})
+
+			want := map[string]string{}
+			for round := 0; round < 20; round++ {
+				err := db.Update(func(tx *Tx) error {
+					for i := 0; i < 800; i++ {
+						k := fmt.Sprintf("k%05d", (i*7919+round*13)%5000)
+						if (i+round)%5 == 0 {
+							if err := tx.Delete([]byte(k)); err == nil {
+								delete(want, k)
+							}
+							continue
+						}
+
+						// Values up to half a page, so some of them overflow
+						v := strings.Repeat(string(rune('a'+round%26)), (i*37+round*101)%(pageSize/2))
+						if err := tx.Put([]byte(k), []byte(v)); err != nil {
+							return err
+						}
+						want[k] = v
+					}
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			checkPageAccounting(t, db)
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// An existing database keeps the page size it was created with
+			db = openTestDB(t, path, &Options{PageSize: MinPageSize})
+			if db.Pager.PageSize() != pageSize {
+				t.Fatalf("reopened with page size %d, want %d", db.Pager.PageSize(), pageSize)
+			}
+			db.View(func(tx *Tx) error {
+				n := 0
+				c := tx.Cursor()
+				for k, v := c.First(); k != nil; k, v = c.Next() {
+					if want[string(k)] != string(v) {
+						t.Fatalf("value of %s does not match", k)
+					}
+					n++
+				}
+				if err := c.Err(); err != nil {
+					t.Fatal(err)
+				}
+				if n != len(want) {
+					t.Fatalf("cursor visited %d keys, want %d", n, len(want))
+				}
+				return nil
+			})
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// With meta page 0 destroyed, meta page 1 is still found at the page size
+			f, err := os.OpenFile(path, os.O_RDWR, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.WriteAt([]byte{1, 2, 3, 4}, 0); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			db = openTestDB(t, path, nil)
+			defer db.Close()
+			if db.Pager.PageSize() != pageSize {
+				t.Fatalf("reopened from meta page 1 with page size %d, want %d", db.Pager.PageSize(), pageSize)
+			}
+		})
+	}
+}
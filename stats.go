@@ -0,0 +1,161 @@
+package gokv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds cumulative counters of a database since it was opened.
+// Take two snapshots and Sub them to get the activity in between.
+type Stats struct {
+	TxN       int64 // transactions started, read and write
+	OpenTxN   int   // read transactions currently open
+	CommitN   int64 // write transactions committed
+	PageAlloc int64 // pages handed out to write transactions
+	SplitN    int64 // leaf and branch node splits
+
+	WriteN       int64         // pages written
+	BytesWritten int64         // bytes written to the file
+	SyncN        int64         // fsync calls
+	SyncTime     time.Duration // total time spent in fsync
+}
+
+// Sub returns the difference between two snapshots of the counters. OpenTxN is taken from s.
+func (s Stats) Sub(other Stats) Stats {
+	return Stats{
+		TxN:          s.TxN - other.TxN,
+		OpenTxN:      s.OpenTxN,
+		CommitN:      s.CommitN - other.CommitN,
+		PageAlloc:    s.PageAlloc - other.PageAlloc,
+		SplitN:       s.SplitN - other.SplitN,
+		WriteN:       s.WriteN - other.WriteN,
+		BytesWritten: s.BytesWritten - other.BytesWritten,
+		SyncN:        s.SyncN - other.SyncN,
+		SyncTime:     s.SyncTime - other.SyncTime,
+	}
+}
+
+// dbStats holds the counters kept by the DB. Read transactions update them concurrently, so they are atomic.
+type dbStats struct {
+	txN       atomic.Int64
+	commitN   atomic.Int64
+	pageAlloc atomic.Int64
+	splitN    atomic.Int64
+}
+
+// pagerStats holds the I/O counters kept by the Pager.
+type pagerStats struct {
+	writeN       atomic.Int64
+	bytesWritten atomic.Int64
+	syncN        atomic.Int64
+	syncTime     atomic.Int64 // nanoseconds
+}
+
+// Stats returns a snapshot of the database's cumulative counters.
+func (db *DB) Stats() Stats {
+	db.metaMu.RLock()
+	openTxN := len(db.readers)
+	db.metaMu.RUnlock()
+
+	p := &db.Pager.stats
+	return Stats{
+		TxN:          db.stats.txN.Load(),
+		OpenTxN:      openTxN,
+		CommitN:      db.stats.commitN.Load(),
+		PageAlloc:    db.stats.pageAlloc.Load(),
+		SplitN:       db.stats.splitN.Load(),
+		WriteN:       p.writeN.Load(),
+		BytesWritten: p.bytesWritten.Load(),
+		SyncN:        p.syncN.Load(),
+		SyncTime:     time.Duration(p.syncTime.Load()),
+	}
+}
+
+// TreeStats describes the shape of a B+ tree, gathered by walking every node from its root.
+type TreeStats struct {
+	Depth     int // levels from the root to the leaves; 1 for a tree that is a single leaf
+	LeafN     int // leaf pages
+	BranchN   int // branch pages
+	OverflowN int // overflow pages holding spilled values
+	KeyN      int // leaf entries, nested buckets included
+
+	FillFactor      float64 // average share of a leaf or branch page taken by the header, offsets and live entries
+	FragmentedBytes int     // bytes of deleted entries still taking space in nodes until they are compacted
+
+	Buckets map[string]*TreeStats // nested buckets by name, each with the stats of its own tree
+}
+
+// Stats walks the top-level tree and every nested bucket as the transaction sees them.
+func (tx *Tx) Stats() (*TreeStats, error) {
+	if tx.db == nil {
+		return nil, ErrTxClosed
+	}
+	return tx.treeStats(tx.root)
+}
+
+// treeStats gathers the stats of the tree rooted at pageID.
+func (tx *Tx) treeStats(pageID int) (*TreeStats, error) {
+	stats := &TreeStats{Buckets: make(map[string]*TreeStats)}
+
+	var usedBytes int
+	err := tx.walkTreeStats(pageID, 1, stats, &usedBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if nodes := stats.LeafN + stats.BranchN; nodes > 0 {
+		stats.FillFactor = float64(usedBytes) / float64(nodes*tx.pageSize())
+	}
+	return stats, nil
+}
+
+// walkTreeStats adds the node at pageID and everything below it to stats.
+func (tx *Tx) walkTreeStats(pageID int, depth int, stats *TreeStats, usedBytes *int) error {
+	node, err := tx.getNode(pageID)
+	if err != nil {
+		return fmt.Errorf("failed to read page %d: %w", pageID, err)
+	}
+
+	stats.Depth = max(stats.Depth, depth)
+	*usedBytes += node.usedBytes()
+	stats.FragmentedBytes += node.fragmentedBytes()
+
+	if node.getType() == NodeBranch {
+		stats.BranchN++
+		for i := uint16(0); i < node.getKeyCount(); i++ {
+			err = tx.walkTreeStats(node.getChild(i), depth+1, stats, usedBytes)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	stats.LeafN++
+	stats.KeyN += int(node.getKeyCount())
+
+	for i := uint16(0); i < node.getKeyCount(); i++ {
+		key, value := node.getLeafKeyValue(i)
+
+		switch {
+		case node.getFlags(i)&EntryFlagBucket != 0:
+			bucket, err := tx.treeStats(int(binary.LittleEndian.Uint64(value)))
+			if err != nil {
+				return err
+			}
+			stats.Buckets[string(key)] = bucket
+
+		case node.getFlags(i)&EntryFlagOverflow != 0:
+			_, length, err := decodeOverflowRef(value)
+			if err != nil {
+				return err
+			}
+			chunkSize := tx.pageSize() - OverflowHeaderSize
+			stats.OverflowN += (length + chunkSize - 1) / chunkSize
+		}
+	}
+
+	return nil
+}
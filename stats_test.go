@@ -0,0 +1,102 @@
+package gokv
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, filepath.Join(dir, "test.db"), nil)
+	defer db.Close()
+
+	before := db.Stats()
+	err := db.Update(func(tx *Tx) error {
+		for i := 0; i < 5000; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), []byte(strings.Repeat("v", i%50))); err != nil {
+				return err
+			}
+		}
+		if err := tx.Put([]byte("big"), make([]byte, 20000)); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket([]byte("b"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 100; i++ {
+			if err := b.Put([]byte(fmt.Sprintf("x%d", i)), []byte("y")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *Tx) error {
+		for i := 0; i < 5000; i += 2 {
+			if err := tx.Delete([]byte(fmt.Sprintf("k%05d", i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.View(func(tx *Tx) error { return nil })
+
+	s := db.Stats().Sub(before)
+	if s.TxN != 3 || s.CommitN != 2 || s.OpenTxN != 0 {
+		t.Errorf("%d transactions, %d commits and %d open, want 3, 2 and 0", s.TxN, s.CommitN, s.OpenTxN)
+	}
+	// Each commit syncs the pages and then the meta page
+	if s.SplitN == 0 || s.PageAlloc == 0 || s.WriteN == 0 || s.BytesWritten == 0 || s.SyncN < 4 {
+		t.Errorf("counters not updated: %+v", s)
+	}
+
+	db.View(func(tx *Tx) error {
+		ts, err := tx.Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The remaining keys, the big value and the bucket
+		if ts.KeyN != 2500+2 {
+			t.Errorf("KeyN = %d, want %d", ts.KeyN, 2500+2)
+		}
+		if ts.OverflowN != 5 {
+			t.Errorf("OverflowN = %d, want 5 pages for a 20000 byte value", ts.OverflowN)
+		}
+		if ts.Depth < 2 || ts.BranchN == 0 || ts.FillFactor <= 0 || ts.FillFactor > 1 {
+			t.Errorf("unexpected tree shape: %+v", ts)
+		}
+		if ts.FragmentedBytes == 0 {
+			t.Errorf("no fragmented bytes after deleting half of the keys")
+		}
+		if b := ts.Buckets["b"]; b == nil || b.KeyN != 100 || b.Depth != 1 {
+			t.Errorf("bucket stats %+v, want a single leaf with 100 keys", b)
+		}
+		return nil
+	})
+
+	// Compacting rewrites the nodes without the deleted entries
+	compacted := filepath.Join(dir, "compacted.db")
+	if err := db.CompactTo(compacted); err != nil {
+		t.Fatal(err)
+	}
+	c := openTestDB(t, compacted, nil)
+	defer c.Close()
+	c.View(func(tx *Tx) error {
+		ts, err := tx.Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ts.KeyN != 2500+2 || ts.FragmentedBytes != 0 {
+			t.Errorf("compacted stats: %d keys and %d fragmented bytes, want %d and 0", ts.KeyN, ts.FragmentedBytes, 2500+2)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,172 @@
+package gokv
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Storage is the file a Pager keeps its pages in. Open uses a FileStorage; OpenStorage accepts any
+// implementation, such as a MemoryStorage to run fully in memory, or a FaultStorage to test failing I/O.
+// ReadAt may be called concurrently with itself and with one writer.
+type Storage interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+
+	// Sync makes the writes so far durable.
+	Sync() error
+
+	// Truncate changes the size of the storage, zero-filling it when it grows.
+	Truncate(size int64) error
+
+	// Size returns the current size of the storage in bytes.
+	Size() (int64, error)
+}
+
+// FileStorage is a Storage backed by a file on disk. It is the only storage that is locked against
+// other processes and that can be memory mapped.
+type FileStorage struct {
+	*os.File
+}
+
+// Size returns the size of the file.
+func (s *FileStorage) Size() (int64, error) {
+	info, err := s.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// MemoryStorage is a Storage that keeps the whole database in memory. Close leaves the contents in place,
+// so a database can be opened again on the same MemoryStorage after it is closed.
+type MemoryStorage struct {
+	mu   sync.RWMutex
+	data []byte
+}
+
+// NewMemoryStorage returns an empty in-memory storage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+// ReadAt reads len(p) bytes at off. Like a file, it returns io.EOF when it reads past the end.
+func (s *MemoryStorage) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if off >= int64(len(s.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, s.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt writes p at off, growing the storage if the write ends past it.
+func (s *MemoryStorage) WriteAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if end := off + int64(len(p)); end > int64(len(s.data)) {
+		s.resize(end)
+	}
+	return copy(s.data[off:], p), nil
+}
+
+// Sync does nothing, memory has nothing to flush.
+func (s *MemoryStorage) Sync() error {
+	return nil
+}
+
+// Truncate changes the size of the storage.
+func (s *MemoryStorage) Truncate(size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resize(size)
+	return nil
+}
+
+// Size returns the size of the storage.
+func (s *MemoryStorage) Size() (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.data)), nil
+}
+
+// Close does nothing, the contents stay available for the next database opened on the storage.
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// resize grows or shrinks the data to size bytes. New bytes are zero.
+func (s *MemoryStorage) resize(size int64) {
+	if size <= int64(cap(s.data)) {
+		old := len(s.data)
+		s.data = s.data[:size]
+		if size > int64(old) {
+			clear(s.data[old:])
+		}
+		return
+	}
+
+	data := make([]byte, size, max(size, 2*int64(cap(s.data))))
+	copy(data, s.data)
+	s.data = data
+}
+
+// FaultStorage wraps another Storage and injects failures, to test how the database copes with I/O errors
+// and with a crash in the middle of a write. The hooks are called before the operation reaches the
+// wrapped storage. Set them before the database is opened, or while no write transaction is running.
+type FaultStorage struct {
+	Storage
+
+	// WriteFault decides the fate of a write of p at off. A nil error lets the write through. Otherwise only
+	// the first n bytes of p are written, which tears the write when n is between 0 and len(p), and the
+	// error is returned.
+	WriteFault func(p []byte, off int64) (n int, err error)
+
+	// SyncFault is returned instead of syncing when it returns a non-nil error.
+	SyncFault func() error
+}
+
+// NewFaultStorage wraps storage in a FaultStorage without any faults set.
+func NewFaultStorage(storage Storage) *FaultStorage {
+	return &FaultStorage{Storage: storage}
+}
+
+// WriteAt writes p at off, unless WriteFault fails or tears the write.
+func (s *FaultStorage) WriteAt(p []byte, off int64) (int, error) {
+	if s.WriteFault == nil {
+		return s.Storage.WriteAt(p, off)
+	}
+
+	n, err := s.WriteFault(p, off)
+	if err == nil {
+		return s.Storage.WriteAt(p, off)
+	}
+
+	n = min(max(n, 0), len(p))
+	if n > 0 {
+		if written, werr := s.Storage.WriteAt(p[:n], off); werr != nil {
+			return written, werr
+		}
+	}
+	return n, err
+}
+
+// Sync syncs the wrapped storage, unless SyncFault fails it.
+func (s *FaultStorage) Sync() error {
+	if s.SyncFault != nil {
+		if err := s.SyncFault(); err != nil {
+			return err
+		}
+	}
+	return s.Storage.Sync()
+}
@@ -0,0 +1,175 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMemoryStorageReopen(t *testing.T) {
+	storage := NewMemoryStorage()
+	db, err := OpenStorage(storage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("a"), []byte("1")) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = OpenStorage(storage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.View(func(tx *Tx) error {
+		if v, err := tx.Get([]byte("a")); err != nil || string(v) != "1" {
+			t.Fatalf("Get(a) after reopening = %q, %v", v, err)
+		}
+		return nil
+	})
+
+	if _, err := OpenStorage(NewMemoryStorage(), &Options{Mmap: true}); err == nil {
+		t.Fatal("memory storage opened with mmap")
+	}
+}
+
+func TestFaultStorage(t *testing.T) {
+	const pageSize = 8192
+	mem := NewMemoryStorage()
+	fs := NewFaultStorage(mem)
+	db, err := OpenStorage(fs, &Options{PageSize: pageSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for round := 0; round < 5; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 500; i++ {
+				if err := tx.Put([]byte(fmt.Sprintf("k%02d-%05d", round, i)), []byte(strings.Repeat("v", i%3000))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Tear the fifth page write of a commit in half
+	boom := errors.New("boom")
+	writes := 0
+	fs.WriteFault = func(p []byte, off int64) (int, error) {
+		writes++
+		if writes == 5 {
+			return len(p) / 2, boom
+		}
+		return 0, nil
+	}
+	err = db.Update(func(tx *Tx) error {
+		for i := 0; i < 500; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("z%05d", i)), []byte("x")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("commit with a torn page write = %v, want the write error", err)
+	}
+	fs.WriteFault = nil
+
+	// Fail the sync of a commit
+	fs.SyncFault = func() error { return boom }
+	err = db.Update(func(tx *Tx) error { return tx.Put([]byte("s"), []byte("x")) })
+	if !errors.Is(err, boom) {
+		t.Fatalf("commit with a failed sync = %v, want the sync error", err)
+	}
+	fs.SyncFault = nil
+
+	// Tear the meta page write of a commit
+	fs.WriteFault = func(p []byte, off int64) (int, error) {
+		if off < 2*pageSize {
+			return 10, boom
+		}
+		return 0, nil
+	}
+	err = db.Update(func(tx *Tx) error { return tx.Put([]byte("m"), []byte("x")) })
+	if !errors.Is(err, boom) {
+		t.Fatalf("commit with a torn meta write = %v, want the write error", err)
+	}
+	fs.WriteFault = nil
+
+	db.View(func(tx *Tx) error {
+		if errs := tx.Check(); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		return nil
+	})
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopened from what reached the storage, the database is at its last good commit
+	db, err = OpenStorage(mem, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.View(func(tx *Tx) error {
+		if errs := tx.Check(); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+
+		n := 0
+		c := tx.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			n++
+		}
+		if err := c.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if n != 2500 {
+			t.Fatalf("%d keys after reopening, want the 2500 of the successful commits", n)
+		}
+		if _, err := tx.Get([]byte("m")); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("Get(m) from the torn commit = %v, want ErrKeyNotFound", err)
+		}
+		return nil
+	})
+
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("after"), []byte("x")) }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompactMemoryStorage(t *testing.T) {
+	db, err := OpenStorage(NewMemoryStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("a"), []byte("1")) }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a file to take the permissions from, the copy is created with the default file mode
+	path := tempDBPath(t)
+	if err := db.CompactTo(path); err != nil {
+		t.Fatal(err)
+	}
+	copied := openTestDB(t, path, nil)
+	defer copied.Close()
+	copied.View(func(tx *Tx) error {
+		if v, err := tx.Get([]byte("a")); err != nil || string(v) != "1" {
+			t.Fatalf("Get(a) from the copy = %q, %v", v, err)
+		}
+		return nil
+	})
+}
@@ -3,84 +3,145 @@ package gokv
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"slices"
+)
+
+// putMode selects how a write treats a key that already exists.
+type putMode int
+
+const (
+	putUpsert  putMode = iota // insert the key or overwrite its value
+	putInsert                 // fail if the key already exists
+	putReplace                // fail if the key does not exist
 )
 
 type Tx struct {
 	db         *DB
 	writable   bool
+	meta       *Meta // snapshot of the meta the transaction started from
 	dirtyNodes map[int]*Node
 	allocated  []int
+	freed      []int
 	root       int
 }
 
 // Get retrieves the value associated with the given key from the database.
+// In a write transaction it sees the transaction's own uncommitted writes.
 func (tx *Tx) Get(key []byte) ([]byte, error) {
-	leaf, err := tx.findLeaf(int(tx.db.Root), key)
-	if err != nil {
-		return nil, err
-	}
-	index, found := leaf.findKeyInNode(key)
+	return tx.rootBucket().Get(key)
+}
 
-	if !found {
-		return nil, fmt.Errorf("key not found")
-	}
+// Put inserts or updates a key-value pair in the database, handling root splits if necessary.
+// Values larger than a quarter of the page size are stored in a chain of overflow pages.
+func (tx *Tx) Put(key []byte, value []byte) error {
+	return tx.rootBucket().Put(key, value)
+}
 
-	_, value := leaf.getLeafKeyValue(index)
+// Insert adds a key-value pair to the database, failing if the key already exists.
+func (tx *Tx) Insert(key []byte, value []byte) error {
+	return tx.rootBucket().Insert(key, value)
+}
 
-	result := make([]byte, len(value))
-	copy(result, value)
+// Replace overwrites the value of an existing key, failing if the key does not exist.
+func (tx *Tx) Replace(key []byte, value []byte) error {
+	return tx.rootBucket().Replace(key, value)
+}
 
-	return result, nil
+// Delete removes a key from the database. Nodes that drop below the fill threshold
+// borrow from or merge with a sibling, and the tree shrinks when the root branch has a single child.
+func (tx *Tx) Delete(key []byte) error {
+	return tx.rootBucket().Delete(key)
 }
 
-// Put inserts or updates a key-value pair in the database, handling root splits if necessary.
-func (tx *Tx) Put(key []byte, value []byte) error {
-	promoteKey, newPageID, err := tx.insertRecursive(tx.root, key, value)
+// putInTree writes an entry into the tree rooted at root according to the mode, handling root splits if necessary.
+// Returns the page of the tree's new root.
+func (tx *Tx) putInTree(root int, key []byte, value []byte, flags byte, mode putMode) (int, error) {
+	rootID, promoteKey, newPageID, err := tx.insertRecursive(root, key, value, flags, mode)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if promoteKey == nil {
-		return nil
+		return rootID, nil
 	}
 
 	// Root split occurred, create a new root node
 	newRootID := tx.allocateNode()
-	newRoot := &Node{data: make([]byte, PageSize)}
+	newRoot := &Node{data: make([]byte, tx.pageSize())}
 	newRoot.data[0] = byte(NodeBranch)
 	binary.LittleEndian.PutUint16(newRoot.data[1:3], 0)
 
-	oldRootNode, err := tx.getNode(tx.root)
+	oldRootNode, err := tx.getNode(rootID)
 	if err != nil {
-		return fmt.Errorf("failed to read old root: %w", err)
+		return 0, fmt.Errorf("failed to read old root: %w", err)
 	}
 	firstKey, _ := oldRootNode.getLeafKeyValue(0)
 
-	err = newRoot.insertBranchKey(firstKey, tx.root)
+	err = newRoot.insertBranchKey(firstKey, rootID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	err = newRoot.insertBranchKey(promoteKey, newPageID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Store in dirtyNodes
 	tx.dirtyNodes[newRootID] = newRoot
 
-	tx.root = newRootID
+	return newRootID, nil
+}
 
-	return nil
+// deleteFromTree removes an entry from the tree rooted at root and returns the page of the tree's new root.
+// Bucket entries can only be removed when deleteBucket is set, and plain values only when it isn't.
+func (tx *Tx) deleteFromTree(root int, key []byte, deleteBucket bool) (int, error) {
+	rootID, found, err := tx.deleteRecursive(root, key, deleteBucket)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, ErrKeyNotFound
+	}
+
+	// Collapse root branches that are left with a single child
+	for {
+		rootNode, err := tx.getNode(rootID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read root: %w", err)
+		}
+		if rootNode.getType() != NodeBranch || rootNode.getKeyCount() != 1 {
+			return rootID, nil
+		}
+
+		oldRootID := rootID
+		rootID = rootNode.getChild(0)
+		tx.freePage(oldRootID)
+	}
 }
+
+// Commit writes the transaction's changes to disk and finishes it. If Commit fails before the new meta page
+// is written, nothing is committed and the transaction stays open until Rollback is called.
 func (tx *Tx) Commit() error {
+	if tx.db == nil {
+		return ErrTxClosed
+	}
 	if !tx.writable {
-		return fmt.Errorf("cannot commit read-only transaction")
+		return fmt.Errorf("cannot commit: %w", ErrTxNotWritable)
 	}
 
+	// Nothing was modified, there is nothing to write
+	if len(tx.dirtyNodes) == 0 && len(tx.freed) == 0 {
+		return tx.Rollback()
+	}
+
+	freelistPages := tx.writeFreelist()
+
 	// flush all dirty pages to disk
 	for pageID, node := range tx.dirtyNodes {
+		node.setChecksum()
 		err := tx.db.Pager.Write(pageID, node.data)
 		if err != nil {
 			return fmt.Errorf("failed to write page %d: %w", pageID, err)
@@ -88,29 +149,91 @@ func (tx *Tx) Commit() error {
 	}
 
 	// sync to ensure data is physically saved
-	err := tx.db.Pager.Sync()
+	if !tx.db.noSync {
+		err := tx.db.Pager.Sync()
+		if err != nil {
+			return fmt.Errorf("failed to sync pager: %w", err)
+		}
+	}
+
+	// write the next Meta Page, pointing to the new root and free list
+	meta := *tx.meta
+	meta.Root = uint32(tx.root)
+	meta.FreeList = 0
+	if len(freelistPages) > 0 {
+		meta.FreeList = uint32(freelistPages[0])
+	}
+	meta.PageCount = uint32(tx.db.Pager.numPages)
+	meta.TxID++
+
+	err := tx.db.writeMeta(&meta)
 	if err != nil {
-		return fmt.Errorf("failed to sync pager: %w", err)
+		return fmt.Errorf("failed to update meta: %w", err)
 	}
 
-	// update the Meta Page if Root changed
-	if tx.root != tx.db.Root {
-		tx.db.Meta.Root = uint32(tx.root)
-		err := tx.db.writeMeta()
-		if err != nil {
-			return fmt.Errorf("failed to update meta: %w", err)
-		}
-		tx.db.Root = tx.root
+	tx.db.metaMu.Lock()
+	tx.db.Meta = &meta
+	tx.db.Root = tx.root
+	tx.db.metaMu.Unlock()
+	tx.db.stats.commitN.Add(1)
+
+	// Pages freed by this transaction and the old free list pages are only reusable once the meta is on disk
+	// and no read transaction can still reach them
+	pending := append(tx.freed, tx.db.Pager.freelistPages...)
+	tx.db.Pager.addPending(meta.TxID, pending)
+	tx.db.Pager.freelistPages = freelistPages
+
+	// Map the pages this commit added to the end of the file. The commit is already durable, so a failure
+	// here is not reported: pages past the old mapping keep being read with ReadAt, and the next commit
+	// tries again, just like when readers keep the mapping in place.
+	tx.db.Pager.remap()
+	tx.close()
+
+	return nil
+}
+
+// Rollback discards the transaction. A write transaction hands the pages it allocated back to the pager,
+// and pages it freed stay in use since its commit never happened. It is safe to call Rollback more than once
+// and after Commit; it then does nothing and returns ErrTxClosed.
+func (tx *Tx) Rollback() error {
+	if tx.db == nil {
+		return ErrTxClosed
+	}
+
+	if tx.writable {
+		tx.releaseAllocated()
+	} else {
+		tx.db.removeReader(tx)
 	}
 
+	tx.close()
 	return nil
 }
 
-func (tx *Tx) Rollback() {
-	// In a full implementation, we would release the allocated pages
-	// back to the free list here.
+// releaseAllocated returns the pages this transaction allocated to the pager. Pages taken from the free list
+// go back on it, and pages past the committed page count are dropped by resetting the page counter.
+func (tx *Tx) releaseAllocated() {
+	p := tx.db.Pager
+	pageCount := int(tx.meta.PageCount)
+
+	for _, pageID := range tx.allocated {
+		if pageID < pageCount {
+			p.ReleasePage(pageID)
+		}
+	}
+	p.numPages = pageCount
+}
+
+// close marks the transaction as finished, drops its modified nodes and lets the next writer in.
+func (tx *Tx) close() {
+	if tx.writable {
+		tx.db.mu.Unlock()
+	}
+
 	tx.db = nil
 	tx.dirtyNodes = nil
+	tx.allocated = nil
+	tx.freed = nil
 }
 
 // findLeaf recursively traverses the B-tree from the given page ID to find the leaf node containing the key.
@@ -126,32 +249,20 @@ func (tx *Tx) findLeaf(pageID int, key []byte) (*Node, error) {
 		return node, nil
 	}
 
-	index, _ := node.findKeyInNode(key)
-
-	// In a branch node, if the key at index is strictly greater than search key,
-	// we need to step back one index to get the correct child.
-	if index < node.getKeyCount() {
-		nodeKey, _ := node.getLeafKeyValue(index)
-		if bytes.Compare(nodeKey, key) > 0 {
-			if index > 0 {
-				index--
-			}
-		}
-	}
-
-	if index >= node.getKeyCount() {
-		index = node.getKeyCount() - 1
-	}
+	index := node.findChildIndex(key)
 
 	childPageID := node.getChild(index)
 	return tx.findLeaf(childPageID, key)
 }
 
 // insertRecursive recursively inserts a key-value pair into the B-tree, handling splits at leaf and branch nodes.
-func (tx *Tx) insertRecursive(pageID int, key []byte, value []byte) (newKey []byte, newPageID int, err error) {
+// An existing key is overwritten in the copied leaf unless the mode forbids it.
+// Modified nodes are copied to new pages, so it returns the page the node at pageID now lives on,
+// plus the promoted key and new sibling page if the node split.
+func (tx *Tx) insertRecursive(pageID int, key []byte, value []byte, flags byte, mode putMode) (nodePageID int, newKey []byte, newPageID int, err error) {
 	node, err := tx.getNode(pageID)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to read page %d: %w", pageID, err)
+		return 0, nil, 0, fmt.Errorf("failed to read page %d: %w", pageID, err)
 	}
 
 	// Make a copy of the node data to avoid modifying the original
@@ -162,131 +273,357 @@ func (tx *Tx) insertRecursive(pageID int, key []byte, value []byte) (newKey []by
 	nodeType := node.getType()
 
 	if nodeType == NodeLeaf {
-		err = node.insertLeafKeyValue(key, value)
+		index, found := node.findKeyInNode(key)
+		if found && (node.getFlags(index)&EntryFlagBucket) != (flags&EntryFlagBucket) {
+			return 0, nil, 0, ErrIncompatibleValue
+		}
+		if found && mode == putInsert {
+			return 0, nil, 0, ErrKeyExists
+		}
+		if !found && mode == putReplace {
+			return 0, nil, 0, ErrKeyNotFound
+		}
+
+		// Drop the old entry from the copy; its overflow chain is only freed once the new value is in place
+		var oldOverflowRef []byte
+		if found {
+			if node.getFlags(index)&EntryFlagOverflow != 0 {
+				_, ref := node.getLeafKeyValue(index)
+				oldOverflowRef = append([]byte(nil), ref...)
+			}
+			node.removeKeyValue(index)
+		}
+
+		err = node.insertLeafKeyValue(key, value, flags)
 		if err == nil {
-			// Store in dirtyNodes instead of writing
-			tx.dirtyNodes[pageID] = node
-			return nil, 0, nil
+			if oldOverflowRef != nil {
+				err = tx.freeOverflow(oldOverflowRef)
+				if err != nil {
+					return 0, nil, 0, err
+				}
+			}
+			return tx.storeNode(pageID, node), nil, 0, nil
 		}
 
-		if err.Error() != "node is full" && err.Error() != "node is full (fragmentation)" {
-			return nil, 0, err
+		if !errors.Is(err, ErrNodeFull) {
+			return 0, nil, 0, err
 		}
 
-		// Node is full, split it
-		newPageID := tx.allocateNode()
-		newNode := &Node{}
-		promoteKey := node.splitLeaf(newNode)
+		// Node is full, split the entries together with the new one by size, so both halves fit
+		pairs := node.getEntries()
+		pairs = slices.Insert(pairs, int(index), kvPair{key, value, flags})
 
-		// Insert the key that caused the split into the appropriate leaf
-		if bytes.Compare(key, promoteKey) < 0 {
-			err = node.insertLeafKeyValue(key, value)
-			if err != nil {
-				return nil, 0, fmt.Errorf("failed to insert key into old leaf after split: %w", err)
-			}
-		} else {
-			err = newNode.insertLeafKeyValue(key, value)
+		leftNode, rightNode, err := splitNode(tx.pageSize(), NodeLeaf, pairs)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+		tx.db.stats.splitN.Add(1)
+
+		if oldOverflowRef != nil {
+			err = tx.freeOverflow(oldOverflowRef)
 			if err != nil {
-				return nil, 0, fmt.Errorf("failed to insert key into new leaf after split: %w", err)
+				return 0, nil, 0, err
 			}
 		}
 
 		// Store in dirtyNodes instead of writing
-		tx.dirtyNodes[pageID] = node
-		tx.dirtyNodes[newPageID] = newNode
+		newPageID := tx.allocateNode()
+		tx.dirtyNodes[newPageID] = rightNode
 
-		return promoteKey, newPageID, nil
+		firstKey, _ := rightNode.getLeafKeyValue(0)
+		return tx.storeNode(pageID, leftNode), cloneBytes(firstKey), newPageID, nil
 	}
 
 	// Branch node: find the correct child to recurse into
-	index, _ := node.findKeyInNode(key)
+	index := node.findChildIndex(key)
+
+	childPageID := node.getChild(index)
+
+	newChildPageID, k, p, err := tx.insertRecursive(childPageID, key, value, flags, mode)
+	if err != nil {
+		return 0, nil, 0, err
+	}
 
-	if index < node.getKeyCount() {
-		nodeKey, _ := node.getLeafKeyValue(index)
-		if bytes.Compare(nodeKey, key) > 0 {
-			if index > 0 {
-				index--
+	// Keys below the first separator are routed to the first child. Lower the separator
+	// so the branch stays sorted when that child splits around a smaller key.
+	lowered := false
+	if index == 0 {
+		firstKey, _ := node.getLeafKeyValue(0)
+		if bytes.Compare(key, firstKey) < 0 {
+			if err := node.clearFirstKey(); err != nil {
+				return 0, nil, 0, err
 			}
+			lowered = true
 		}
 	}
 
-	if index >= node.getKeyCount() {
-		index = node.getKeyCount() - 1
+	if newChildPageID == childPageID && k == nil && !lowered {
+		return pageID, nil, 0, nil
 	}
 
-	childPageID := node.getChild(index)
-
-	k, p, err := tx.insertRecursive(childPageID, key, value)
-	if err != nil {
-		return nil, 0, err
-	}
+	// The child moved to a new page, point this branch at it
+	node.setChild(index, newChildPageID)
 
 	if k == nil {
-		return nil, 0, nil
+		return tx.storeNode(pageID, node), nil, 0, nil
 	}
 
-	// Make a copy of the node data to avoid modifying the original
-	branchNodeData := make([]byte, len(node.data))
-	copy(branchNodeData, node.data)
-	node = &Node{data: branchNodeData}
-
 	// Child split occurred, insert the promoted key into this branch node
 	err = node.insertBranchKey(k, p)
 
 	if err == nil {
-		// Store in dirtyNodes instead of writing
-		tx.dirtyNodes[pageID] = node
-		return nil, 0, nil
+		return tx.storeNode(pageID, node), nil, 0, nil
 	}
 
-	// Branch node is also full, split it
-	if err.Error() == "node is full" || err.Error() == "node is full (fragmentation)" {
-		newBranchPageID := tx.allocateNode()
-		newBranchNode := &Node{data: make([]byte, PageSize)}
+	if !errors.Is(err, ErrNodeFull) {
+		return 0, nil, 0, err
+	}
 
-		promoteBranchKey := node.splitBranch(newBranchNode)
+	// Branch node is also full, split it. The new child goes right after the one that split,
+	// and the first key of the new branch is promoted.
+	pairs := node.getEntries()
+	pairs = slices.Insert(pairs, int(index)+1, kvPair{key: k, val: encodePageID(p)})
 
-		// Insert the pending key into the appropriate branch node
-		if bytes.Compare(k, promoteBranchKey) < 0 {
-			err = node.insertBranchKey(k, p)
-			if err != nil {
-				return nil, 0, fmt.Errorf("failed to insert key into old branch node after split: %w", err)
-			}
-		} else {
-			err = newBranchNode.insertBranchKey(k, p)
+	leftNode, rightNode, err := splitNode(tx.pageSize(), NodeBranch, pairs)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	tx.db.stats.splitN.Add(1)
+
+	// Store in dirtyNodes instead of writing
+	newBranchPageID := tx.allocateNode()
+	tx.dirtyNodes[newBranchPageID] = rightNode
+
+	promoteBranchKey, _ := rightNode.getLeafKeyValue(0)
+	return tx.storeNode(pageID, leftNode), cloneBytes(promoteBranchKey), newBranchPageID, nil
+}
+
+// splitNode divides the entries of an overfull node between two new nodes of the given type and page size.
+func splitNode(pageSize int, nodeType uint16, pairs []kvPair) (*Node, *Node, error) {
+	split := splitIndex(pairs)
+	leftPairs, rightPairs := pairs[:split], pairs[split:]
+
+	// Keys are limited to MaxKeySize, so this only happens with a key written before the limit existed
+	if entriesSize(leftPairs) > pageSize || entriesSize(rightPairs) > pageSize {
+		return nil, nil, fmt.Errorf("%w: entry too large to split node", ErrKeyTooLarge)
+	}
+
+	left, err := newNodeFromEntries(pageSize, nodeType, leftPairs)
+	if err != nil {
+		return nil, nil, err
+	}
+	right, err := newNodeFromEntries(pageSize, nodeType, rightPairs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return left, right, nil
+}
+
+// deleteRecursive removes the key from the subtree rooted at pageID, rebalancing underflowing children on the way back up.
+// Returns the page the node at pageID now lives on and whether the key was found.
+func (tx *Tx) deleteRecursive(pageID int, key []byte, deleteBucket bool) (int, bool, error) {
+	node, err := tx.getNode(pageID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read page %d: %w", pageID, err)
+	}
+
+	// Make a copy of the node data to avoid modifying the original
+	nodeData := make([]byte, len(node.data))
+	copy(nodeData, node.data)
+	node = &Node{data: nodeData}
+
+	if node.getType() == NodeLeaf {
+		index, found := node.findKeyInNode(key)
+		if !found {
+			return pageID, false, nil
+		}
+
+		if (node.getFlags(index)&EntryFlagBucket != 0) != deleteBucket {
+			return 0, false, ErrIncompatibleValue
+		}
+
+		if node.getFlags(index)&EntryFlagOverflow != 0 {
+			_, ref := node.getLeafKeyValue(index)
+			err = tx.freeOverflow(ref)
 			if err != nil {
-				return nil, 0, fmt.Errorf("failed to insert key into new branch node after split: %w", err)
+				return 0, false, err
 			}
 		}
 
-		// Store in dirtyNodes instead of writing
-		tx.dirtyNodes[pageID] = node
-		tx.dirtyNodes[newBranchPageID] = newBranchNode
+		node.removeKeyValue(index)
+		return tx.storeNode(pageID, node), true, nil
+	}
+
+	index := node.findChildIndex(key)
+	childPageID := node.getChild(index)
+
+	newChildPageID, found, err := tx.deleteRecursive(childPageID, key, deleteBucket)
+	if err != nil || !found {
+		return pageID, found, err
+	}
+
+	node.setChild(index, newChildPageID)
+
+	child, err := tx.getNode(newChildPageID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read page %d: %w", newChildPageID, err)
+	}
+
+	if child.underflow() {
+		err = tx.rebalanceChild(node, index)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	return tx.storeNode(pageID, node), true, nil
+}
+
+// rebalanceChild fixes an underflowing child of parent by merging it with a sibling,
+// or by redistributing the entries of both when they don't fit in a single page.
+func (tx *Tx) rebalanceChild(parent *Node, index uint16) error {
+	count := parent.getKeyCount()
+	if count < 2 {
+		// No sibling to work with; the caller collapses single-child roots
+		return nil
+	}
+
+	leftIndex := index
+	if index == count-1 {
+		leftIndex = index - 1
+	}
+	rightIndex := leftIndex + 1
+
+	leftPageID := parent.getChild(leftIndex)
+	rightPageID := parent.getChild(rightIndex)
+
+	left, err := tx.getNode(leftPageID)
+	if err != nil {
+		return fmt.Errorf("failed to read page %d: %w", leftPageID, err)
+	}
+	right, err := tx.getNode(rightPageID)
+	if err != nil {
+		return fmt.Errorf("failed to read page %d: %w", rightPageID, err)
+	}
 
-		return promoteBranchKey, newBranchPageID, nil
+	nodeType := left.getType()
+	pairs := append(left.getEntries(), right.getEntries()...)
+
+	// Both siblings fit in one page: merge right into left and drop right from the parent
+	if entriesSize(pairs) <= tx.pageSize() {
+		merged, err := newNodeFromEntries(tx.pageSize(), nodeType, pairs)
+		if err != nil {
+			return err
+		}
+		parent.setChild(leftIndex, tx.storeNode(leftPageID, merged))
+		parent.removeKeyValue(rightIndex)
+		tx.freePage(rightPageID)
+		return nil
 	}
 
-	return nil, 0, err
+	// Otherwise borrow: split the combined entries evenly by size
+	split := splitIndex(pairs)
+
+	leftPairs, rightPairs := pairs[:split], pairs[split:]
+
+	// The right sibling's separator in the parent becomes its new first key
+	parentPairs := parent.getEntries()
+	parentPairs[rightIndex].key = rightPairs[0].key
+
+	// Leave the child underfull rather than overflow a page
+	if entriesSize(leftPairs) > tx.pageSize() || entriesSize(rightPairs) > tx.pageSize() || entriesSize(parentPairs) > tx.pageSize() {
+		return nil
+	}
+
+	newParent, err := newNodeFromEntries(tx.pageSize(), NodeBranch, parentPairs)
+	if err != nil {
+		return err
+	}
+	newLeft, err := newNodeFromEntries(tx.pageSize(), nodeType, leftPairs)
+	if err != nil {
+		return err
+	}
+	newRight, err := newNodeFromEntries(tx.pageSize(), nodeType, rightPairs)
+	if err != nil {
+		return err
+	}
+
+	parent.data = newParent.data
+	parent.setChild(leftIndex, tx.storeNode(leftPageID, newLeft))
+	parent.setChild(rightIndex, tx.storeNode(rightPageID, newRight))
+
+	return nil
+}
+
+// pageSize returns the page size of the database, which is the size of every node.
+func (tx *Tx) pageSize() int {
+	return tx.db.Pager.pageSize
 }
 
+// getNode returns the leaf or branch node at pageID, from dirtyNodes if this transaction modified it,
+// otherwise from disk after verifying its checksum and layout, so corruption surfaces as a *CorruptionError
+// instead of a bad slice access. With mmap enabled, a node read from disk points into the mapping and must be
+// copied before it is modified.
 func (tx *Tx) getNode(pageID int) (*Node, error) {
+	node, dirty, err := tx.getPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dirty {
+		if err := node.validate(pageID); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+// getPage returns the page at pageID as a node of any type, from dirtyNodes if this transaction modified it,
+// otherwise from disk after verifying its checksum. Reports whether the page came from dirtyNodes.
+func (tx *Tx) getPage(pageID int) (*Node, bool, error) {
 	if node, ok := tx.dirtyNodes[pageID]; ok {
-		return node, nil
+		return node, true, nil
 	}
 
 	data, err := tx.db.Pager.Read(pageID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	return &Node{
+	node := &Node{
 		data: data,
-	}, nil
+	}
+	if !node.verifyChecksum() {
+		return nil, false, &CorruptionError{PageID: pageID, Offset: ChecksumOffset, Reason: "checksum mismatch"}
+	}
+
+	return node, false, nil
 }
 
 // allocateNode allocates a new page and tracks it in the transaction
 func (tx *Tx) allocateNode() int {
 	pageID := tx.db.Pager.GetFreePage()
 	tx.allocated = append(tx.allocated, pageID)
+	tx.db.stats.pageAlloc.Add(1)
+	return pageID
+}
+
+// storeNode puts a modified copy of the node at pageID into dirtyNodes and returns the page it now lives on.
+// Pages allocated by this transaction are updated in place. Committed pages are never overwritten:
+// the copy moves to a fresh page, and the old page is freed once the transaction commits.
+func (tx *Tx) storeNode(pageID int, node *Node) int {
+	if _, ok := tx.dirtyNodes[pageID]; !ok {
+		tx.freePage(pageID)
+		pageID = tx.allocateNode()
+	}
+
+	tx.dirtyNodes[pageID] = node
 	return pageID
 }
+
+// freePage marks a page as no longer used by the tree. It is handed back to the pager on commit.
+func (tx *Tx) freePage(pageID int) {
+	delete(tx.dirtyNodes, pageID)
+	tx.freed = append(tx.freed, pageID)
+}
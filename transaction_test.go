@@ -0,0 +1,294 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCopyOnWrite(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	rng := rand.New(rand.NewSource(4))
+	want := map[string]bool{}
+	for round := 0; round < 30; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 100; i++ {
+				k := fmt.Sprintf("k%05d", rng.Intn(2000))
+				if want[k] {
+					if err := tx.Delete([]byte(k)); err != nil {
+						return err
+					}
+					delete(want, k)
+					continue
+				}
+
+				if err := tx.Put([]byte(k), make([]byte, rng.Intn(3000))); err != nil {
+					return err
+				}
+				want[k] = true
+			}
+
+			// No page of the committed tree may be written over before the commit.
+			live := map[int]bool{0: true, 1: true}
+			markReachable(t, tx, tx.db.Root, live)
+			for _, id := range tx.db.Pager.freelistPages {
+				live[id] = true
+			}
+			for id := range tx.dirtyNodes {
+				if live[id] {
+					t.Fatalf("round %d: page %d of the committed tree was modified in place", round, id)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPageAccounting(t, db)
+	}
+}
+
+func TestPutInsertReplace(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	rng := rand.New(rand.NewSource(5))
+	want := map[string]string{}
+	for round := 0; round < 30; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 100; i++ {
+				k := fmt.Sprintf("k%04d", rng.Intn(500))
+				// Mix small values, values that fill a good part of a node and overflow values
+				v := make([]byte, []int{10, 500, 1500, 9000}[rng.Intn(4)])
+				rng.Read(v)
+				_, exists := want[k]
+
+				switch rng.Intn(3) {
+				case 0:
+					if err := tx.Put([]byte(k), v); err != nil {
+						return err
+					}
+					want[k] = string(v)
+				case 1:
+					err := tx.Insert([]byte(k), v)
+					if exists && !errors.Is(err, ErrKeyExists) || !exists && err != nil {
+						return fmt.Errorf("Insert(%s) with existing key %v: %w", k, exists, err)
+					}
+					if !exists {
+						want[k] = string(v)
+					}
+				case 2:
+					err := tx.Replace([]byte(k), v)
+					if exists && err != nil || !exists && !errors.Is(err, ErrKeyNotFound) {
+						return fmt.Errorf("Replace(%s) with existing key %v: %w", k, exists, err)
+					}
+					if exists {
+						want[k] = string(v)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPageAccounting(t, db)
+	}
+
+	db.View(func(tx *Tx) error {
+		for k, v := range want {
+			got, err := tx.Get([]byte(k))
+			if err != nil || string(got) != v {
+				t.Fatalf("Get(%s) returned %d bytes, %v, want %d bytes", k, len(got), err, len(v))
+			}
+		}
+		return nil
+	})
+}
+
+func TestReadersSeeTheirSnapshot(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	err := db.Update(func(tx *Tx) error {
+		for i := 0; i < 500; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%04d", i)), []byte("v0")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- db.View(func(tx *Tx) error {
+			close(started)
+			for iter := 0; iter < 50; iter++ {
+				n := 0
+				c := tx.Cursor()
+				for k, v := c.First(); k != nil; k, v = c.Next() {
+					if string(v) != "v0" {
+						return fmt.Errorf("cursor saw %s = %q after the snapshot", k, v)
+					}
+					got, err := tx.Get(k)
+					if err != nil || string(got) != "v0" {
+						return fmt.Errorf("Get(%s) = %q, %v after the snapshot", k, got, err)
+					}
+					n++
+				}
+				if err := c.Err(); err != nil {
+					return err
+				}
+				if n != 500 {
+					return fmt.Errorf("cursor saw %d keys, want 500", n)
+				}
+			}
+			return nil
+		})
+	}()
+
+	// Rewrite and delete keys while the reader scans its snapshot
+	<-started
+	for round := 1; round < 30; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 500; i += 3 {
+				k := []byte(fmt.Sprintf("k%04d", i))
+				if round%2 == 0 {
+					if err := tx.Delete(k); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := tx.Put(k, []byte(fmt.Sprintf("v%d-%s", round, make([]byte, round*40)))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	checkPageAccounting(t, db)
+}
+
+func TestReadYourWrites(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	err := db.Update(func(tx *Tx) error {
+		// Enough keys to split the root, so later reads go through new branch pages
+		for i := 0; i < 3000; i++ {
+			k := []byte(fmt.Sprintf("k%05d", i))
+			if err := tx.Put(k, []byte(fmt.Sprint(i))); err != nil {
+				return err
+			}
+			v, err := tx.Get(k)
+			if err != nil || string(v) != fmt.Sprint(i) {
+				t.Fatalf("Get(%s) right after Put = %q, %v", k, v, err)
+			}
+		}
+
+		if err := tx.Delete([]byte("k00005")); err != nil {
+			return err
+		}
+		if _, err := tx.Get([]byte("k00005")); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("Get of a deleted key = %v, want ErrKeyNotFound", err)
+		}
+
+		n := 0
+		c := tx.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			n++
+		}
+		if err := c.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if n != 2999 {
+			t.Fatalf("cursor visited %d keys, want 2999", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRollbackReleasesAllocatedPages(t *testing.T) {
+	db := openTestDB(t, tempDBPath(t), nil)
+	defer db.Close()
+
+	err := db.Update(func(tx *Tx) error {
+		for i := 0; i < 2000; i++ {
+			if err := tx.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Free some pages, so rolled back transactions allocate from the free list as well as past its end
+	err = db.Update(func(tx *Tx) error {
+		for i := 0; i < 1000; i++ {
+			if err := tx.Delete([]byte(fmt.Sprintf("k%05d", i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(tx *Tx) error { return tx.Put([]byte("x"), []byte("y")) }); err != nil {
+		t.Fatal(err)
+	}
+
+	db.releaseFreedPages()
+	pageCount, freeCount := db.Pager.numPages, len(db.Pager.freePages)
+	if freeCount == 0 {
+		t.Fatal("no free pages to allocate from")
+	}
+
+	rollback := errors.New("rollback")
+	for round := 0; round < 5; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for i := 0; i < 3000; i++ {
+				if err := tx.Put([]byte(fmt.Sprintf("z%05d", i)), []byte(strings.Repeat("v", 2000))); err != nil {
+					return err
+				}
+			}
+			return rollback
+		})
+		if err != rollback {
+			t.Fatalf("Update = %v, want the function's error", err)
+		}
+
+		db.releaseFreedPages()
+		if db.Pager.numPages != pageCount || len(db.Pager.freePages) != freeCount {
+			t.Fatalf("round %d: %d pages with %d free after rollback, want %d with %d free",
+				round, db.Pager.numPages, len(db.Pager.freePages), pageCount, freeCount)
+		}
+	}
+
+	db.View(func(tx *Tx) error {
+		if errs := tx.Check(); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		return nil
+	})
+	checkPageAccounting(t, db)
+}